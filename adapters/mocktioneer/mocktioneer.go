@@ -0,0 +1,2725 @@
+package mocktioneer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/gofrs/uuid"
+	"github.com/prebid/openrtb/v20/adcom1"
+	"github.com/prebid/openrtb/v20/openrtb2"
+	"github.com/prebid/prebid-server/v3/adapters"
+	"github.com/prebid/prebid-server/v3/config"
+	"github.com/prebid/prebid-server/v3/currency"
+	"github.com/prebid/prebid-server/v3/errortypes"
+	"github.com/prebid/prebid-server/v3/macros"
+	"github.com/prebid/prebid-server/v3/openrtb_ext"
+	"github.com/prebid/prebid-server/v3/util/jsonutil"
+	"github.com/prebid/prebid-server/v3/util/randomutil"
+	"github.com/prebid/prebid-server/v3/util/uuidutil"
+	"golang.org/x/net/http/httpguts"
+	xcurrency "golang.org/x/text/currency"
+)
+
+type adapter struct {
+	endpoint           string
+	endpoints          []weightedEndpoint
+	mediaTypeEndpoints map[openrtb_ext.BidType]*template.Template
+	fallbackEndpoint   *template.Template
+	requestTemplate    *template.Template
+	randomGenerator    randomutil.RandomGenerator
+	uuidGenerator      uuidutil.UUIDGenerator
+	sleep              func(time.Duration)
+	extraInfo          extraInfo
+}
+
+type weightedEndpoint struct {
+	template *template.Template
+	weight   int
+}
+
+// extraInfo holds the optional adapter settings read from the ExtraAdapterInfo config.
+type extraInfo struct {
+	// RequestTemplate is a text/template which renders the outgoing request body. The template is
+	// executed with the bid request as data and may use the "json" func to marshal any value.
+	RequestTemplate string `json:"requestTemplate,omitempty"`
+
+	// RequireHTTPS rejects endpoints which don't use the https scheme.
+	RequireHTTPS bool `json:"requireHTTPS,omitempty"`
+
+	// ConvertResponseCurrency converts bid prices to the request's first currency in the adapter
+	// instead of leaving the conversion to the core. Only the request's custom currency rates are
+	// available to the adapter.
+	ConvertResponseCurrency bool `json:"convertResponseCurrency,omitempty"`
+
+	// Endpoints replaces the configured endpoint with several weighted ones. One is picked for each
+	// auction, and EndpointSeed makes the sequence of picks reproducible.
+	Endpoints    []endpointInfo `json:"endpoints,omitempty"`
+	EndpointSeed *int64         `json:"endpointSeed,omitempty"`
+
+	// MediaTypeEndpoints sends imps of the mapped media types to their own endpoint, in a request per
+	// endpoint. Imps of other media types, or of several, go to FallbackEndpoint, or to the regular
+	// endpoints when there's no fallback either.
+	MediaTypeEndpoints map[openrtb_ext.BidType]string `json:"mediaTypeEndpoints,omitempty"`
+	FallbackEndpoint   string                         `json:"fallbackEndpoint,omitempty"`
+
+	// BidIDSeed makes the bid ids generated for bids without any id reproducible.
+	BidIDSeed *int64 `json:"bidIDSeed,omitempty"`
+
+	// EmptyResponseOnNoBid returns an empty response instead of nil when mocktioneer doesn't bid, so
+	// the currency is still reported.
+	EmptyResponseOnNoBid bool `json:"emptyResponseOnNoBid,omitempty"`
+
+	// NoBidStatusCodes are the response statuses meaning mocktioneer didn't bid, and OkStatusCodes
+	// those whose body is a bid response. They default to 204 and 200, any other status is an error.
+	NoBidStatusCodes []int `json:"noBidStatusCodes,omitempty"`
+	OkStatusCodes    []int `json:"okStatusCodes,omitempty"`
+
+	// MaxBidsPerResponse caps how many bids are read from a single response, counting the copies for
+	// duplicate imps. Zero means unlimited.
+	MaxBidsPerResponse int `json:"maxBidsPerResponse,omitempty"`
+
+	// MaxResponseDepth caps the JSON nesting depth of responses. Defaults to defaultMaxResponseDepth.
+	MaxResponseDepth int `json:"maxResponseDepth,omitempty"`
+
+	// ResolveAdmMacros replaces the OpenRTB auction macros, such as ${AUCTION_PRICE}, in the bid adm
+	// and nurl. By default both are passed through untouched.
+	ResolveAdmMacros bool `json:"resolveAdmMacros,omitempty"`
+
+	// OrderBidsByImp sorts the returned bids by the order of their imps in the request, and by
+	// descending price within an imp, so the output doesn't depend on the order of the response.
+	OrderBidsByImp bool `json:"orderBidsByImp,omitempty"`
+
+	// TopBidPerImpOnly keeps only the highest-priced bid of each imp, the first one on a tie. The
+	// number of bids dropped is reported as a warning.
+	TopBidPerImpOnly bool `json:"topBidPerImpOnly,omitempty"`
+
+	// DedupeImps sends only the first of the imps which are identical but for their id. The bids
+	// for it are copied to each of its duplicates, with the duplicate's id appended to the bid id.
+	DedupeImps bool `json:"dedupeImps,omitempty"`
+
+	// TimeoutHeader names the header the request's tmax is sent in, since mock deployments differ.
+	// Defaults to defaultTimeoutHeader.
+	TimeoutHeader string `json:"timeoutHeader,omitempty"`
+
+	// DefaultCurrency is the currency mocktioneer bids in when neither the request nor the response
+	// names one. Defaults to USD, which the core assumes as well.
+	DefaultCurrency string `json:"defaultCurrency,omitempty"`
+
+	// LenientCurrency sends the unconverted bidfloor-derived bid param with a warning when the floor
+	// can't be converted to the request currency. By default the imp is rejected.
+	LenientCurrency bool `json:"lenientCurrency,omitempty"`
+
+	// SplitDeals sends imps with pmp deals and open auction imps to mocktioneer in separate requests.
+	SplitDeals bool `json:"splitDeals,omitempty"`
+
+	// StrictParams rejects imps whose bidder ext can't be parsed instead of sending them without an
+	// ext.
+	StrictParams bool `json:"strictParams,omitempty"`
+
+	// ImpWarningThreshold warns about requests with more imps than this, without failing them, so
+	// unusually large requests get noticed. Zero disables the warning.
+	ImpWarningThreshold int `json:"impWarningThreshold,omitempty"`
+
+	// MinImps skips requests with fewer imps than this, as a clean no-bid, for scenarios with
+	// batch-only demand. Defaults to 1, so every request is sent.
+	MinImps int `json:"minImps,omitempty"`
+
+	// CompressRequest gzips request bodies larger than CompressThreshold bytes, which defaults to
+	// defaultCompressThreshold. Unlike the bidder info endpointCompression it leaves small requests
+	// uncompressed, so the two shouldn't be combined.
+	CompressRequest   bool `json:"compressRequest,omitempty"`
+	CompressThreshold int  `json:"compressThreshold,omitempty"`
+
+	// MaxTargetingKeyLength truncates the targeting keys of bid.ext.prebid.targeting. The account's
+	// truncate setting isn't passed to adapters, so it defaults to the core's default, 20.
+	MaxTargetingKeyLength int `json:"maxTargetingKeyLength,omitempty"`
+
+	// RequestHmacKey signs each request body, as sent, with HMAC-SHA256. The hex encoded signature
+	// goes in the X-Request-Signature header.
+	RequestHmacKey string `json:"requestHmacKey,omitempty"`
+
+	// ExtraHeaders are added to every request, e.g. for deployments behind an auth proxy. Headers the
+	// adapter sets itself take precedence unless OverrideExisting is set.
+	ExtraHeaders     map[string]string `json:"extraHeaders,omitempty"`
+	OverrideExisting bool              `json:"overrideExisting,omitempty"`
+
+	// FollowRedirects reports the Location of a 3xx response. Adapters can't ask the core to retry a
+	// request elsewhere, and the core's http client already follows redirects it can, so the
+	// response is still an error; configure the regional endpoint directly instead.
+	FollowRedirects bool `json:"followRedirects,omitempty"`
+
+	// MinCpm and MaxCpm clamp bid prices by media type, in the currency mocktioneer responds with.
+	// Media types without a bound are unbounded. Clamped bids are noted in debug auctions.
+	MinCpm map[openrtb_ext.BidType]float64 `json:"minCpm,omitempty"`
+	MaxCpm map[openrtb_ext.BidType]float64 `json:"maxCpm,omitempty"`
+
+	// ValidateEndpointDNS fails the Builder when an endpoint's host doesn't resolve, to catch typos
+	// at startup. Endpoints with macros are skipped since their host isn't known until an auction.
+	ValidateEndpointDNS bool `json:"validateEndpointDNS,omitempty"`
+
+	// BlockedAdomains drops bids for any of these advertiser domains or their subdomains, like the
+	// request's badv. Domains are matched case-insensitively.
+	BlockedAdomains []string `json:"blockedAdomains,omitempty"`
+
+	// SeatMediaTypes maps seat names to the media type of their bids, for mock seats standing in for
+	// a single kind of demand. It applies to bids whose type isn't set by the bid or the seatbid.
+	SeatMediaTypes map[string]openrtb_ext.BidType `json:"seatMediaTypes,omitempty"`
+
+	// CridPrefix is prepended to the creative id of every bid so mock creatives stand out in
+	// downstream logs. Bids without a crid get a generated one.
+	CridPrefix string `json:"cridPrefix,omitempty"`
+}
+
+// defaultMaxResponseDepth is far deeper than any legitimate bid response nests.
+const defaultMaxResponseDepth = 128
+
+// defaultMaxTargetingKeyLength matches the core's default targeting key length.
+const defaultMaxTargetingKeyLength = 20
+
+// defaultTimeoutHeader is the header mocktioneer reads the request's tmax from by default.
+const defaultTimeoutHeader = "X-Timeout-Ms"
+
+// adapterHeaders are the headers the adapter sets on requests itself, which the timeout header
+// mustn't replace.
+var adapterHeaders = []string{
+	"Accept",
+	"Accept-Language",
+	"Content-Encoding",
+	"Content-Type",
+	"DNT",
+	"User-Agent",
+	"X-Ad-Position",
+	"X-Auction-Type",
+	"X-COPPA",
+	"X-Click-Browser",
+	"X-Connection-Group",
+	"X-Content-Cat",
+	"X-Content-Genre",
+	"X-Debug",
+	"X-Display-Manager",
+	"X-Final-Decision",
+	"X-Forwarded-For",
+	"X-Limit-Ad-Tracking",
+	"X-Openrtb-Version",
+	"X-Request-Id",
+	"X-Request-Signature",
+	"X-Scenario-Id",
+	"X-Seed",
+	"X-User-Segment-Count",
+	"X-Video-Placement",
+}
+
+// defaultCompressThreshold is about where gzip starts saving more than the overhead it adds.
+const defaultCompressThreshold = 1024
+
+// maxAdmLength bounds the adm param, which is sent upstream with every request for the imp.
+const maxAdmLength = 64 * 1024
+
+// lookupHost resolves endpoint hosts for the validateEndpointDNS option.
+var lookupHost = net.LookupHost
+
+// utf8BOM is the UTF-8 encoded byte order mark.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// maxDelayResponseMs bounds the delayResponseMs param well below any sensible auction timeout
+// ceiling.
+const maxDelayResponseMs = 5000
+
+type endpointInfo struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Builder builds a new instance of the Mocktioneer adapter for the given bidder with the given config.
+func Builder(bidderName openrtb_ext.BidderName, config config.Adapter, server config.Server) (adapters.Bidder, error) {
+	var info extraInfo
+	if len(config.ExtraAdapterInfo) > 0 {
+		if err := jsonutil.Unmarshal([]byte(config.ExtraAdapterInfo), &info); err != nil {
+			return nil, fmt.Errorf("invalid extra info: %v", err)
+		}
+	}
+
+	if info.MaxBidsPerResponse < 0 {
+		return nil, errors.New("maxBidsPerResponse must not be negative")
+	}
+	if info.MaxResponseDepth < 0 {
+		return nil, errors.New("maxResponseDepth must not be negative")
+	}
+	if info.ImpWarningThreshold < 0 {
+		return nil, errors.New("impWarningThreshold must not be negative")
+	}
+	if info.MaxResponseDepth == 0 {
+		info.MaxResponseDepth = defaultMaxResponseDepth
+	}
+	if info.MinImps < 0 {
+		return nil, errors.New("minImps must not be negative")
+	}
+	if info.MinImps == 0 {
+		info.MinImps = 1
+	}
+	if info.MaxTargetingKeyLength < 0 {
+		return nil, errors.New("maxTargetingKeyLength must not be negative")
+	}
+	if info.MaxTargetingKeyLength == 0 {
+		info.MaxTargetingKeyLength = defaultMaxTargetingKeyLength
+	}
+	if info.CompressThreshold < 0 {
+		return nil, errors.New("compressThreshold must not be negative")
+	}
+	if info.CompressThreshold == 0 {
+		info.CompressThreshold = defaultCompressThreshold
+	}
+	if len(info.NoBidStatusCodes) == 0 {
+		info.NoBidStatusCodes = []int{http.StatusNoContent}
+	}
+	if len(info.OkStatusCodes) == 0 {
+		info.OkStatusCodes = []int{http.StatusOK}
+	}
+	if err := validateStatusCodes(info.NoBidStatusCodes, info.OkStatusCodes); err != nil {
+		return nil, err
+	}
+	for i, domain := range info.BlockedAdomains {
+		info.BlockedAdomains[i] = normalizeDomain(domain)
+	}
+	if info.DefaultCurrency == "" {
+		info.DefaultCurrency = "USD"
+	}
+	if !isValidCurrency(info.DefaultCurrency) {
+		return nil, fmt.Errorf("defaultCurrency %s is not a 3-letter ISO 4217 currency code", info.DefaultCurrency)
+	}
+	if info.TimeoutHeader == "" {
+		info.TimeoutHeader = defaultTimeoutHeader
+	}
+	if !httpguts.ValidHeaderFieldName(info.TimeoutHeader) {
+		return nil, fmt.Errorf("invalid timeout header name %q", info.TimeoutHeader)
+	}
+	for _, name := range adapterHeaders {
+		if strings.EqualFold(info.TimeoutHeader, name) {
+			return nil, fmt.Errorf("timeout header %s is set by the adapter itself", info.TimeoutHeader)
+		}
+	}
+	for name, value := range info.ExtraHeaders {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid extra header name %q", name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid value for extra header %s", name)
+		}
+	}
+
+	if err := validateCpmBounds(info.MinCpm, info.MaxCpm); err != nil {
+		return nil, err
+	}
+	for seat, bidType := range info.SeatMediaTypes {
+		if _, err := openrtb_ext.ParseBidType(string(bidType)); err != nil {
+			return nil, fmt.Errorf("seatMediaTypes for seat %s: %v", seat, err)
+		}
+	}
+
+	endpoints, err := buildEndpoints(config.Endpoint, info.Endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaTypeEndpoints, fallbackEndpoint, err := buildMediaTypeEndpoints(info.MediaTypeEndpoints, info.FallbackEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	if info.ValidateEndpointDNS {
+		if err := validateEndpointDNS(configuredEndpointURLs(config.Endpoint, info)); err != nil {
+			return nil, err
+		}
+	}
+
+	bidder := &adapter{
+		endpoint:           config.Endpoint,
+		endpoints:          endpoints,
+		mediaTypeEndpoints: mediaTypeEndpoints,
+		fallbackEndpoint:   fallbackEndpoint,
+		randomGenerator:    randomutil.RandomNumberGenerator{},
+		uuidGenerator:      uuidutil.UUIDRandomGenerator{},
+		sleep:              time.Sleep,
+		extraInfo:          info,
+	}
+
+	if info.EndpointSeed != nil {
+		bidder.randomGenerator = newSeededRandomGenerator(*info.EndpointSeed)
+	}
+	if info.BidIDSeed != nil {
+		bidder.uuidGenerator = newSeededUUIDGenerator(*info.BidIDSeed)
+	}
+
+	// Endpoints are resolved with the same empty macros as for each request, so checking them once
+	// at startup covers every request.
+	if info.RequireHTTPS {
+		for _, endpointURL := range configuredEndpointURLs(config.Endpoint, info) {
+			endpointTemplate, err := template.New("endpointTemplate").Parse(endpointURL)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse endpoint url template: %v", err)
+			}
+			resolved, err := buildEndpointURL(endpointTemplate)
+			if err != nil {
+				return nil, err
+			}
+			if parsed, err := url.Parse(resolved); err != nil || parsed.Scheme != "https" {
+				return nil, fmt.Errorf("endpoint must use https: %s", resolved)
+			}
+		}
+	}
+
+	if info.RequestTemplate != "" {
+		bidder.requestTemplate, err = template.New("requestTemplate").Funcs(requestTemplateFuncs).Parse(info.RequestTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse request template: %v", err)
+		}
+	}
+	return bidder, nil
+}
+
+// redacted replaces secrets in the output of Config.
+const redacted = "<REDACTED>"
+
+// Config returns the adapter's resolved configuration for admin tooling: the endpoint template and
+// the options which are set, defaults included. The request HMAC key and extra header values,
+// which may hold credentials, are redacted.
+func (a *adapter) Config() map[string]interface{} {
+	info := a.extraInfo
+	if info.RequestHmacKey != "" {
+		info.RequestHmacKey = redacted
+	}
+	if len(info.ExtraHeaders) > 0 {
+		info.ExtraHeaders = make(map[string]string, len(a.extraInfo.ExtraHeaders))
+		for name := range a.extraInfo.ExtraHeaders {
+			info.ExtraHeaders[name] = redacted
+		}
+	}
+
+	resolved := make(map[string]interface{})
+	if infoJSON, err := jsonutil.Marshal(info); err == nil {
+		_ = jsonutil.Unmarshal(infoJSON, &resolved)
+	}
+	if len(info.Endpoints) == 0 {
+		resolved["endpoint"] = a.endpoint
+	}
+	return resolved
+}
+
+// buildEndpoints parses the endpoint templates. The configured endpoint is only used when extra
+// info doesn't list weighted endpoints.
+func buildEndpoints(defaultEndpoint string, endpointInfos []endpointInfo) ([]weightedEndpoint, error) {
+	if len(endpointInfos) == 0 {
+		endpointInfos = []endpointInfo{{URL: defaultEndpoint, Weight: 1}}
+	}
+
+	endpoints := make([]weightedEndpoint, 0, len(endpointInfos))
+	for _, info := range endpointInfos {
+		if info.Weight <= 0 {
+			return nil, fmt.Errorf("endpoint %s must have a weight greater than 0", info.URL)
+		}
+		endpoint, err := template.New("endpointTemplate").Parse(info.URL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse endpoint url template: %v", err)
+		}
+		endpoints = append(endpoints, weightedEndpoint{template: endpoint, weight: info.Weight})
+	}
+	return endpoints, nil
+}
+
+// configuredEndpointURLs returns the url of every endpoint the adapter can send to: the weighted
+// endpoints or else the default endpoint, then the media type endpoints and the fallback endpoint.
+func configuredEndpointURLs(defaultEndpoint string, info extraInfo) []string {
+	var endpointURLs []string
+	if len(info.Endpoints) == 0 {
+		endpointURLs = append(endpointURLs, defaultEndpoint)
+	}
+	for _, endpoint := range info.Endpoints {
+		endpointURLs = append(endpointURLs, endpoint.URL)
+	}
+
+	bidTypes := make([]string, 0, len(info.MediaTypeEndpoints))
+	for bidType := range info.MediaTypeEndpoints {
+		bidTypes = append(bidTypes, string(bidType))
+	}
+	sort.Strings(bidTypes)
+	for _, bidType := range bidTypes {
+		endpointURLs = append(endpointURLs, info.MediaTypeEndpoints[openrtb_ext.BidType(bidType)])
+	}
+	if info.FallbackEndpoint != "" {
+		endpointURLs = append(endpointURLs, info.FallbackEndpoint)
+	}
+	return endpointURLs
+}
+
+// buildMediaTypeEndpoints parses the mediaTypeEndpoints and fallbackEndpoint templates. The fallback
+// only applies along with media type endpoints.
+func buildMediaTypeEndpoints(endpointURLs map[openrtb_ext.BidType]string, fallbackURL string) (map[openrtb_ext.BidType]*template.Template, *template.Template, error) {
+	if len(endpointURLs) == 0 {
+		if fallbackURL != "" {
+			return nil, nil, errors.New("fallbackEndpoint requires mediaTypeEndpoints")
+		}
+		return nil, nil, nil
+	}
+
+	endpoints := make(map[openrtb_ext.BidType]*template.Template, len(endpointURLs))
+	for bidType, endpointURL := range endpointURLs {
+		if _, err := openrtb_ext.ParseBidType(string(bidType)); err != nil {
+			return nil, nil, fmt.Errorf("mediaTypeEndpoints: %v", err)
+		}
+		endpoint, err := template.New("endpointTemplate").Parse(endpointURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to parse %s endpoint url template: %v", bidType, err)
+		}
+		endpoints[bidType] = endpoint
+	}
+
+	if fallbackURL == "" {
+		return endpoints, nil, nil
+	}
+	fallback, err := template.New("endpointTemplate").Parse(fallbackURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse fallback endpoint url template: %v", err)
+	}
+	return endpoints, fallback, nil
+}
+
+// validateEndpointDNS checks the host of each endpoint without macros resolves.
+func validateEndpointDNS(endpointURLs []string) error {
+	for _, endpointURL := range endpointURLs {
+		if strings.Contains(endpointURL, "{{") {
+			continue
+		}
+		parsed, err := url.Parse(endpointURL)
+		if err != nil || parsed.Hostname() == "" {
+			return fmt.Errorf("endpoint %s has no host to resolve", endpointURL)
+		}
+		if _, err := lookupHost(parsed.Hostname()); err != nil {
+			return fmt.Errorf("unable to resolve endpoint host %s: %v", parsed.Hostname(), err)
+		}
+	}
+	return nil
+}
+
+// seededRandomGenerator is a randomutil.RandomGenerator which yields a reproducible sequence. It is
+// shared across auctions so the rand.Rand is guarded by a mutex.
+type seededRandomGenerator struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newSeededRandomGenerator(seed int64) *seededRandomGenerator {
+	return &seededRandomGenerator{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (g *seededRandomGenerator) GenerateInt63() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rand.Int63()
+}
+
+func (g *seededRandomGenerator) Intn(n int) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rand.Intn(n)
+}
+
+// seededUUIDGenerator is a uuidutil.UUIDGenerator which yields a reproducible sequence of version 4
+// UUIDs. Like seededRandomGenerator it is shared across auctions.
+type seededUUIDGenerator struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newSeededUUIDGenerator(seed int64) *seededUUIDGenerator {
+	return &seededUUIDGenerator{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (g *seededUUIDGenerator) Generate() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var id uuid.UUID
+	if _, err := g.rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	id.SetVersion(uuid.V4)
+	id.SetVariant(uuid.VariantRFC4122)
+	return id.String(), nil
+}
+
+var requestTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := jsonutil.Marshal(v)
+		return string(b), err
+	},
+}
+
+func (a *adapter) MakeRequests(request *openrtb2.BidRequest, reqInfo *adapters.ExtraRequestInfo) ([]*adapters.RequestData, []error) {
+	if len(request.Imp) < a.extraInfo.MinImps {
+		return nil, nil
+	}
+
+	var errs []error
+	if a.extraInfo.ImpWarningThreshold > 0 && len(request.Imp) > a.extraInfo.ImpWarningThreshold {
+		errs = append(errs, &errortypes.Warning{
+			Message: fmt.Sprintf("request has %d imps, more than the warning threshold of %d", len(request.Imp), a.extraInfo.ImpWarningThreshold),
+		})
+	}
+
+	bidCurrency := a.extraInfo.DefaultCurrency
+	if len(request.Cur) > 0 {
+		bidCurrency = request.Cur[0]
+	}
+
+	requestFloor, err := getRequestBidFloor(request)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	var duplicates map[string][]string
+	if a.extraInfo.DedupeImps {
+		duplicates = findDuplicateImps(request.Imp)
+	}
+	isDuplicate := make(map[string]bool)
+	for _, ids := range duplicates {
+		for _, id := range ids {
+			isDuplicate[id] = true
+		}
+	}
+
+	imps := make([]openrtb2.Imp, 0, len(request.Imp))
+	// Imps are routed before they're prepared, which may clear the mediaType param.
+	routes := make(map[string]*template.Template)
+	var unparseableImpIDs []string
+	var inputImps int
+	for _, imp := range request.Imp {
+		if isDuplicate[imp.ID] {
+			continue
+		}
+		inputImps++
+		if route := a.routeImp(&imp); route != nil {
+			routes[imp.ID] = route
+		}
+		if _, err := parseImpExt(&imp); err != nil && len(imp.Ext) > 0 && !a.extraInfo.StrictParams {
+			unparseableImpIDs = append(unparseableImpIDs, imp.ID)
+		}
+		if imp.BidFloor == 0 && requestFloor > 0 {
+			imp.BidFloor = requestFloor
+			imp.BidFloorCur = bidCurrency
+		}
+		impErrs, ok := a.prepareImp(&imp, reqInfo, bidCurrency)
+		errs = append(errs, impErrs...)
+		if ok {
+			imps = append(imps, imp)
+		}
+	}
+	if len(imps) == 0 {
+		return nil, errs
+	}
+	// Without strictParams unparseable exts are cleared, which would leave nothing to send when every
+	// imp of the request is unparseable.
+	if len(unparseableImpIDs) == inputImps {
+		return nil, append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("no valid imps remained: the mocktioneer params of every imp failed to parse (%s)", strings.Join(unparseableImpIDs, ", ")),
+		})
+	}
+	if request.Test == 1 {
+		if err := describeClearedImps(imps); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if delay := getResponseDelay(request); delay > 0 {
+		capped, err := capDelay(delay, request.TMax)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		a.sleep(capped)
+	}
+
+	scenario, err := getScenario(request)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	ortbVersion, versionErrs := getOrtbVersion(request)
+	errs = append(errs, versionErrs...)
+	connGroup := getConnGroup(request)
+	seed, err := getSeed(request)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	impParams := getImpParams(request)
+	// The weighted endpoint is picked once so every request of the auction goes to the same one.
+	endpoint := a.selectEndpoint()
+
+	var requests []*adapters.RequestData
+	for _, group := range a.groupImps(imps, routes) {
+		// The imps of a group share their route.
+		groupEndpoint := endpoint
+		if route, ok := routes[group[0].ID]; ok {
+			groupEndpoint = route
+		}
+		requestData, err := a.makeRequest(request, group, groupEndpoint, scenario, ortbVersion, connGroup, seed)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, checkExpectedEndpoints(group, requestData.Uri, impParams)...)
+		// The duplicates are covered by the request as well, e.g. when the core rejects its imps.
+		for _, imp := range group {
+			requestData.ImpIDs = append(requestData.ImpIDs, duplicates[imp.ID]...)
+		}
+		requests = append(requests, requestData)
+	}
+	return requests, errs
+}
+
+// signBody returns the hex encoded HMAC-SHA256 of the body.
+func signBody(body []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// gzipBody compresses a request body.
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, fmt.Errorf("unable to compress request body: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("unable to compress request body: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// groupImps returns the imps to send in each request. That's a single request unless splitDeals is
+// set, in which case imps with deals and open auction imps are sent separately, or imps are routed
+// by media type, in which case each endpoint of routes, keyed by imp id, gets its own request.
+func (a *adapter) groupImps(imps []openrtb2.Imp, routes map[string]*template.Template) [][]openrtb2.Imp {
+	groups := a.splitDeals(imps)
+	if len(a.mediaTypeEndpoints) == 0 {
+		return groups
+	}
+
+	var routed [][]openrtb2.Imp
+	for _, group := range groups {
+		var groupRoutes []*template.Template
+		byRoute := make(map[*template.Template][]openrtb2.Imp)
+		for _, imp := range group {
+			route := routes[imp.ID]
+			if _, ok := byRoute[route]; !ok {
+				groupRoutes = append(groupRoutes, route)
+			}
+			byRoute[route] = append(byRoute[route], imp)
+		}
+		for _, route := range groupRoutes {
+			routed = append(routed, byRoute[route])
+		}
+	}
+	return routed
+}
+
+// splitDeals separates imps with deals from open auction imps when the splitDeals option is set.
+func (a *adapter) splitDeals(imps []openrtb2.Imp) [][]openrtb2.Imp {
+	if !a.extraInfo.SplitDeals {
+		return [][]openrtb2.Imp{imps}
+	}
+
+	var dealImps, openImps []openrtb2.Imp
+	for _, imp := range imps {
+		if imp.PMP != nil && len(imp.PMP.Deals) > 0 {
+			dealImps = append(dealImps, imp)
+		} else {
+			openImps = append(openImps, imp)
+		}
+	}
+
+	groups := make([][]openrtb2.Imp, 0, 2)
+	for _, group := range [][]openrtb2.Imp{dealImps, openImps} {
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// makeRequest builds the request to mocktioneer for the given imps.
+func (a *adapter) makeRequest(request *openrtb2.BidRequest, imps []openrtb2.Imp, endpointTemplate *template.Template, scenario, ortbVersion, connGroup, seed string) (*adapters.RequestData, error) {
+	requestCopy := *request
+	requestCopy.Imp = imps
+	if isCOPPA(request) && request.User != nil {
+		// Child-directed requests mustn't carry identifiers mocktioneer could personalize with.
+		user := *request.User
+		user.ID = ""
+		user.EIDs = nil
+		requestCopy.User = &user
+	}
+
+	endpoint, err := buildEndpointURL(endpointTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := a.buildRequestBody(&requestCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := getHeaders(&requestCopy)
+	if scenario != "" {
+		headers.Add("X-Scenario-Id", scenario)
+	}
+	if ortbVersion != "" {
+		headers.Set("X-Openrtb-Version", ortbVersion)
+	}
+	if connGroup != "" {
+		headers.Add("X-Connection-Group", connGroup)
+	}
+	if seed != "" {
+		headers.Add("X-Seed", seed)
+	}
+	if request.TMax > 0 {
+		headers.Set(a.extraInfo.TimeoutHeader, strconv.FormatInt(request.TMax, 10))
+	}
+	a.addExtraHeaders(headers)
+
+	if a.extraInfo.CompressRequest && len(body) > a.extraInfo.CompressThreshold {
+		if body, err = gzipBody(body); err != nil {
+			return nil, err
+		}
+		headers.Set("Content-Encoding", "gzip")
+	}
+	if a.extraInfo.RequestHmacKey != "" {
+		headers.Set("X-Request-Signature", signBody(body, a.extraInfo.RequestHmacKey))
+	}
+
+	return &adapters.RequestData{
+		Method:  http.MethodPost,
+		Uri:     endpoint,
+		Body:    body,
+		Headers: headers,
+		ImpIDs:  openrtb_ext.GetImpIDs(requestCopy.Imp),
+	}, nil
+}
+
+// addExtraHeaders merges the configured extra headers into the request headers.
+func (a *adapter) addExtraHeaders(headers http.Header) {
+	for name, value := range a.extraInfo.ExtraHeaders {
+		if _, exists := headers[http.CanonicalHeaderKey(name)]; exists && !a.extraInfo.OverrideExisting {
+			continue
+		}
+		headers.Set(name, value)
+	}
+}
+
+// prepareImp rewrites the imp ext into what mocktioneer expects. The bid and adm params are
+// forwarded so the mock can echo them as the bid price and markup. Imps without either are sent
+// without an ext and mocktioneer falls back to its own pricing and creative. Imps with invalid
+// params are reported and left out of the request. An unparseable ext is dropped along with the
+// imp with strictParams set, and otherwise cleared. A bid derived from the bidfloor is converted
+// to bidCurrency, the currency mocktioneer bids in.
+func (a *adapter) prepareImp(imp *openrtb2.Imp, reqInfo *adapters.ExtraRequestInfo, bidCurrency string) ([]error, bool) {
+	impExt, err := parseImpExt(imp)
+	if err != nil {
+		if a.extraInfo.StrictParams {
+			return []error{err}, false
+		}
+		imp.Ext = nil
+		return nil, true
+	}
+
+	// Params under ext.prebid.bidder.mocktioneer are copied to ext.bidder, where mocktioneer reads them.
+	if _, _, _, err := jsonparser.Get(imp.Ext, "bidder"); err != nil {
+		if params, _, _, err := jsonparser.Get(imp.Ext, "prebid", "bidder", string(openrtb_ext.BidderMocktioneer)); err == nil {
+			if imp.Ext, err = setJSON(imp.Ext, params, "bidder"); err != nil {
+				return []error{err}, false
+			}
+		}
+	}
+
+	if impExt.MediaType != "" && !isSupportedMediaType(impExt.MediaType) {
+		return []error{&errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: unsupported mediaType %s", imp.ID, impExt.MediaType),
+		}}, false
+	}
+
+	var errs []error
+	if impExt.ExpectBidCount != nil && *impExt.ExpectBidCount < 0 {
+		errs = append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: expectBidCount must not be negative", imp.ID),
+		})
+	}
+
+	if impExt.OrtbVersion != "" && !isSupportedOrtbVersion(impExt.OrtbVersion) {
+		errs = append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: unsupported ortbVersion %s", imp.ID, impExt.OrtbVersion),
+		})
+	}
+
+	if impExt.ConnGroup != "" && !httpguts.ValidHeaderFieldValue(impExt.ConnGroup) {
+		errs = append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: connGroup is not a valid header value", imp.ID),
+		})
+	}
+
+	if impExt.ExpectEndpoint != "" {
+		if parsed, err := url.Parse(impExt.ExpectEndpoint); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			errs = append(errs, &errortypes.BadInput{
+				Message: fmt.Sprintf("imp %s: expectEndpoint must be an absolute url", imp.ID),
+			})
+		}
+	}
+
+	if impExt.Cur != "" && !isValidCurrency(impExt.Cur) {
+		return append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: cur %s is not a 3-letter ISO 4217 currency code", imp.ID, impExt.Cur),
+		}), false
+	}
+
+	if impExt.StatusCode != 0 && (impExt.StatusCode < 100 || impExt.StatusCode > 599) {
+		return append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: statusCode %d is not an HTTP status code", imp.ID, impExt.StatusCode),
+		}), false
+	}
+
+	if impExt.PriceFloor != nil && *impExt.PriceFloor < 0 {
+		return append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: priceFloor must not be negative", imp.ID),
+		}), false
+	}
+	if impExt.PriceFloorCur != "" && !isValidCurrency(impExt.PriceFloorCur) {
+		return append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: priceFloorCur %s is not a 3-letter ISO 4217 currency code", imp.ID, impExt.PriceFloorCur),
+		}), false
+	}
+	// The floor params override the imp's floor each on their own, so a test can change just the
+	// currency or just the amount.
+	if impExt.PriceFloor != nil {
+		imp.BidFloor = *impExt.PriceFloor
+	}
+	if impExt.PriceFloorCur != "" {
+		imp.BidFloorCur = impExt.PriceFloorCur
+	}
+
+	if impExt.WinNotice != "" {
+		if parsed, err := url.Parse(impExt.WinNotice); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return append(errs, &errortypes.BadInput{
+				Message: fmt.Sprintf("imp %s: winNotice must be an absolute https url", imp.ID),
+			}), false
+		}
+	}
+
+	if len(impExt.AdM) > maxAdmLength {
+		return append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: adm is longer than %d bytes", imp.ID, maxAdmLength),
+		}), false
+	}
+
+	if impExt.DelayResponseMs < 0 || impExt.DelayResponseMs > maxDelayResponseMs {
+		errs = append(errs, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: delayResponseMs must be between 0 and %d", imp.ID, maxDelayResponseMs),
+		})
+	}
+
+	// A forced error is forwarded with the rest of the params. It can't be combined with a price
+	// since the mock won't bid either way.
+	if impExt.ForceError {
+		if impExt.Bid != nil || impExt.BidFloorMultiplier != 0 {
+			return append(errs, &errortypes.BadInput{
+				Message: fmt.Sprintf("imp %s: forceError can't be combined with bid or bidFloorMultiplier", imp.ID),
+			}), false
+		}
+		return errs, true
+	}
+
+	// A bid param which is explicitly 0 is still forwarded, only an absent one isn't.
+	bid := impExt.Bid
+	if impExt.BidFloorMultiplier != 0 {
+		switch {
+		case impExt.BidFloorMultiplier < 0:
+			errs = append(errs, &errortypes.BadInput{
+				Message: fmt.Sprintf("imp %s: bidFloorMultiplier must be greater than 0", imp.ID),
+			})
+		case bid != nil:
+			errs = append(errs, &errortypes.Warning{
+				Message: fmt.Sprintf("imp %s: bid param takes precedence over bidFloorMultiplier", imp.ID),
+			})
+		case imp.BidFloor > 0:
+			floor, err := convertBidFloor(imp, reqInfo, bidCurrency)
+			if err != nil {
+				if !a.extraInfo.LenientCurrency {
+					return append(errs, &errortypes.BadInput{
+						Message: fmt.Sprintf("imp %s: %v", imp.ID, err),
+					}), false
+				}
+				errs = append(errs, &errortypes.Warning{
+					Message: fmt.Sprintf("imp %s: %v, using the unconverted bidfloor", imp.ID, err),
+				})
+				floor = imp.BidFloor
+			}
+			multiplied := floor * impExt.BidFloorMultiplier
+			bid = &multiplied
+		}
+	}
+
+	if bid == nil && impExt.AdM == "" && impExt.Cur == "" && impExt.WinNotice == "" && impExt.PriceFloor == nil && impExt.PriceFloorCur == "" && impExt.StatusCode == 0 {
+		imp.Ext = nil
+		return errs, true
+	}
+	if bid != impExt.Bid {
+		if imp.Ext, err = setJSON(imp.Ext, []byte(strconv.FormatFloat(*bid, 'f', -1, 64)), "bidder", "bid"); err != nil {
+			return append(errs, err), false
+		}
+	}
+	return errs, true
+}
+
+// convertBidFloor returns the imp's bidfloor in the given currency. Floors without a currency are
+// assumed to be in it already.
+func convertBidFloor(imp *openrtb2.Imp, reqInfo *adapters.ExtraRequestInfo, to string) (float64, error) {
+	if imp.BidFloorCur == "" || strings.EqualFold(imp.BidFloorCur, to) {
+		return imp.BidFloor, nil
+	}
+	if reqInfo == nil || reqInfo.CurrencyConversions == nil {
+		return 0, fmt.Errorf("unable to convert bidfloor from %s to %s: no currency rates", imp.BidFloorCur, to)
+	}
+
+	floor, err := reqInfo.ConvertCurrency(imp.BidFloor, imp.BidFloorCur, to)
+	if err != nil {
+		return 0, fmt.Errorf("unable to convert bidfloor from %s to %s: %v", imp.BidFloorCur, to, err)
+	}
+	return floor, nil
+}
+
+// getOrtbVersion returns the imp's ortbVersion param, which overrides the X-Openrtb-Version header.
+// It's only applied to single-imp requests, where it can't disagree with another imp.
+func getOrtbVersion(request *openrtb2.BidRequest) (string, []error) {
+	if len(request.Imp) == 1 {
+		impExt, err := parseImpExt(&request.Imp[0])
+		if err != nil || !isSupportedOrtbVersion(impExt.OrtbVersion) {
+			return "", nil
+		}
+		return impExt.OrtbVersion, nil
+	}
+
+	var errs []error
+	for i := range request.Imp {
+		if impExt, err := parseImpExt(&request.Imp[i]); err == nil && impExt.OrtbVersion != "" {
+			errs = append(errs, &errortypes.Warning{
+				Message: fmt.Sprintf("imp %s: ortbVersion ignored, it only applies to single-imp requests", request.Imp[i].ID),
+			})
+		}
+	}
+	return "", errs
+}
+
+func isSupportedOrtbVersion(version string) bool {
+	return version == "2.5" || version == "2.6"
+}
+
+// getRequestBidFloor returns the request-level bidFloor param, the default floor of imps without
+// one. It's in the request's bid currency.
+func getRequestBidFloor(request *openrtb2.BidRequest) (float64, error) {
+	floor, err := jsonparser.GetFloat(request.Ext, "prebid", "bidderparams", "bidFloor")
+	if errors.Is(err, jsonparser.KeyPathNotFoundError) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, &errortypes.BadInput{
+			Message: fmt.Sprintf("invalid request bidFloor: %v", err),
+		}
+	}
+	if floor < 0 {
+		return 0, &errortypes.BadInput{
+			Message: "request bidFloor must not be negative",
+		}
+	}
+	return floor, nil
+}
+
+// getConnGroup returns the connection group the load balancer in front of mocktioneer pins the
+// request to, from the first imp with a valid connGroup param.
+func getConnGroup(request *openrtb2.BidRequest) string {
+	for i := range request.Imp {
+		if impExt, err := parseImpExt(&request.Imp[i]); err == nil && impExt.ConnGroup != "" && httpguts.ValidHeaderFieldValue(impExt.ConnGroup) {
+			return impExt.ConnGroup
+		}
+	}
+	return ""
+}
+
+// getSeed returns the seed mocktioneer makes its output reproducible with, from the first imp with
+// a seed param or else the request-level one.
+func getSeed(request *openrtb2.BidRequest) (string, error) {
+	for i := range request.Imp {
+		if impExt, err := parseImpExt(&request.Imp[i]); err == nil && impExt.Seed != nil {
+			return strconv.FormatInt(*impExt.Seed, 10), nil
+		}
+	}
+
+	seed, err := jsonparser.GetInt(request.Ext, "prebid", "bidderparams", "seed")
+	if errors.Is(err, jsonparser.KeyPathNotFoundError) {
+		return "", nil
+	}
+	if err != nil {
+		return "", &errortypes.BadInput{
+			Message: "request seed must be an integer",
+		}
+	}
+	return strconv.FormatInt(seed, 10), nil
+}
+
+// getScenario returns the id of the mock scenario mocktioneer should answer with. The imp's scenario
+// param wins for single-imp requests, otherwise the request-level one is used. The core narrows
+// ext.prebid.bidderparams down to mocktioneer's own params before calling the adapter.
+func getScenario(request *openrtb2.BidRequest) (string, error) {
+	if len(request.Imp) == 1 {
+		if impExt, err := parseImpExt(&request.Imp[0]); err == nil && impExt.Scenario != "" {
+			return validateScenario(impExt.Scenario)
+		}
+	}
+
+	scenario, err := jsonparser.GetString(request.Ext, "prebid", "bidderparams", "scenario")
+	if errors.Is(err, jsonparser.KeyPathNotFoundError) {
+		return "", nil
+	}
+	if err != nil {
+		return "", &errortypes.BadInput{
+			Message: fmt.Sprintf("invalid scenario id: %v", err),
+		}
+	}
+	return validateScenario(scenario)
+}
+
+// validateScenario checks the scenario id is a non-empty HTTP token, so it's safe to send as a header.
+func validateScenario(scenario string) (string, error) {
+	if !httpguts.ValidHeaderFieldName(scenario) {
+		return "", &errortypes.BadInput{
+			Message: fmt.Sprintf("invalid scenario id %q: it must be a non-empty token", scenario),
+		}
+	}
+	return scenario, nil
+}
+
+// getResponseDelay returns how long MakeRequests waits to simulate a slow adapter: the longest valid
+// delayResponseMs of the request's imps. The param only applies to test requests.
+func getResponseDelay(request *openrtb2.BidRequest) time.Duration {
+	if request.Test != 1 {
+		return 0
+	}
+
+	var delayMs int
+	for i := range request.Imp {
+		impExt, err := parseImpExt(&request.Imp[i])
+		if err != nil || impExt.DelayResponseMs > maxDelayResponseMs {
+			continue
+		}
+		delayMs = max(delayMs, impExt.DelayResponseMs)
+	}
+	return time.Duration(delayMs) * time.Millisecond
+}
+
+// capDelay keeps a simulated delay within the request's tmax. Adapters aren't given the auction's
+// context, so the budget is the whole tmax from the start of MakeRequests. An exceeded budget is
+// reported the way a timed out bidder would be, as a warning.
+func capDelay(delay time.Duration, tmaxMs int64) (time.Duration, error) {
+	budget := time.Duration(tmaxMs) * time.Millisecond
+	if budget <= 0 || delay <= budget {
+		return delay, nil
+	}
+	return budget, &errortypes.Warning{
+		Message: fmt.Sprintf("simulated delay of %v cut to the request's tmax of %v, the auction would have timed out", delay, budget),
+	}
+}
+
+func isSupportedMediaType(mediaType string) bool {
+	switch openrtb_ext.BidType(mediaType) {
+	case openrtb_ext.BidTypeBanner, openrtb_ext.BidTypeVideo, openrtb_ext.BidTypeNative, openrtb_ext.BidTypeAudio:
+		return true
+	}
+	return false
+}
+
+// describeClearedImps lists the imps sent without bidder params, either because they have none
+// mocktioneer reads or because their ext couldn't be parsed. It's a diagnostic for test requests.
+func describeClearedImps(imps []openrtb2.Imp) error {
+	var impIDs []string
+	for _, imp := range imps {
+		if _, _, _, err := jsonparser.Get(imp.Ext, "bidder"); err != nil {
+			impIDs = append(impIDs, imp.ID)
+		}
+	}
+	if len(impIDs) == 0 {
+		return nil
+	}
+	return &errortypes.Warning{
+		Message: fmt.Sprintf("imps sent to mocktioneer without bidder params: %s", strings.Join(impIDs, ", ")),
+	}
+}
+
+// checkFloor warns about a bid priced below its imp's bidfloor, which is where the floors module
+// puts the floor it enforces. The floor is in the imp's bidfloorcur and bidCurrency is the currency
+// of the price.
+func checkFloor(request *openrtb2.BidRequest, bid *openrtb2.Bid, imp *openrtb2.Imp, bidCurrency string) error {
+	if imp == nil || imp.BidFloor <= 0 {
+		return nil
+	}
+
+	floorCurrency := imp.BidFloorCur
+	if floorCurrency == "" {
+		floorCurrency = "USD"
+	}
+	floor := imp.BidFloor
+	if !strings.EqualFold(floorCurrency, bidCurrency) {
+		rate, err := getRequestRate(request, floorCurrency, bidCurrency)
+		if err != nil {
+			return &errortypes.Warning{
+				Message: fmt.Sprintf("bid %s: unable to check it against the floor of imp %s: %v", bid.ID, imp.ID, err),
+			}
+		}
+		floor *= rate
+	}
+
+	if bid.Price < floor {
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s is priced at %v %s, below the floor %v %s of imp %s", bid.ID, bid.Price, bidCurrency, imp.BidFloor, floorCurrency, imp.ID),
+		}
+	}
+	return nil
+}
+
+// routeImp returns the endpoint of the imp's media type, or else the fallback endpoint. It's nil
+// when neither applies and the imp goes to the regular endpoints.
+func (a *adapter) routeImp(imp *openrtb2.Imp) *template.Template {
+	if len(a.mediaTypeEndpoints) == 0 {
+		return nil
+	}
+	if bidType, ok := mediaTypeForImp(imp); ok {
+		if endpoint, ok := a.mediaTypeEndpoints[bidType]; ok {
+			return endpoint
+		}
+	}
+	return a.fallbackEndpoint
+}
+
+// selectEndpoint picks an endpoint with a probability proportional to its weight.
+func (a *adapter) selectEndpoint() *template.Template {
+	if len(a.endpoints) == 1 {
+		return a.endpoints[0].template
+	}
+
+	totalWeight := 0
+	for _, endpoint := range a.endpoints {
+		totalWeight += endpoint.weight
+	}
+
+	pick := a.randomGenerator.Intn(totalWeight)
+	for _, endpoint := range a.endpoints {
+		if pick < endpoint.weight {
+			return endpoint.template
+		}
+		pick -= endpoint.weight
+	}
+	return a.endpoints[len(a.endpoints)-1].template
+}
+
+func buildEndpointURL(endpointTemplate *template.Template) (string, error) {
+	endpoint, err := macros.ResolveMacros(endpointTemplate, macros.EndpointTemplateParams{})
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve endpoint macros: %v", err)
+	}
+	return endpoint, nil
+}
+
+// buildRequestBody marshals the request, or renders it with the configured request template.
+func (a *adapter) buildRequestBody(request *openrtb2.BidRequest) ([]byte, error) {
+	if a.requestTemplate == nil {
+		return jsonutil.Marshal(request)
+	}
+
+	var body bytes.Buffer
+	if err := a.requestTemplate.Execute(&body, request); err != nil {
+		return nil, fmt.Errorf("unable to execute request template: %v", err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, errors.New("request template did not render valid JSON")
+	}
+	return body.Bytes(), nil
+}
+
+func parseImpExt(imp *openrtb2.Imp) (*openrtb_ext.ExtMocktioneer, error) {
+	var bidderExt adapters.ExtImpBidder
+	if err := jsonutil.Unmarshal(imp.Ext, &bidderExt); err != nil {
+		return nil, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: invalid ext: %v", imp.ID, err),
+		}
+	}
+
+	// Some test harnesses send the params under the newer ext.prebid.bidder.mocktioneer instead.
+	params := bidderExt.Bidder
+	if len(params) == 0 && bidderExt.Prebid != nil {
+		if prebidParams, ok := bidderExt.Prebid.Bidder[string(openrtb_ext.BidderMocktioneer)]; ok {
+			params = prebidParams
+		}
+	}
+
+	var mocktioneerExt openrtb_ext.ExtMocktioneer
+	if err := jsonutil.Unmarshal(params, &mocktioneerExt); err != nil {
+		return nil, &errortypes.BadInput{
+			Message: fmt.Sprintf("imp %s: invalid ext.bidder: %v", imp.ID, err),
+		}
+	}
+	return &mocktioneerExt, nil
+}
+
+func getHeaders(request *openrtb2.BidRequest) http.Header {
+	headers := http.Header{}
+	headers.Add("Content-Type", "application/json;charset=utf-8")
+	headers.Add("Accept", "application/json")
+	headers.Add("X-Openrtb-Version", "2.6")
+	if request.ID != "" {
+		headers.Add("X-Request-Id", request.ID)
+	}
+
+	if request.Device != nil {
+		if len(request.Device.UA) > 0 {
+			headers.Add("User-Agent", request.Device.UA)
+		}
+		if len(request.Device.IPv6) > 0 {
+			headers.Add("X-Forwarded-For", request.Device.IPv6)
+		}
+		if len(request.Device.IP) > 0 {
+			headers.Add("X-Forwarded-For", request.Device.IP)
+		}
+		if request.Device.Lmt != nil {
+			headers.Add("X-Limit-Ad-Tracking", strconv.Itoa(int(*request.Device.Lmt)))
+		}
+		if request.Device.DNT != nil {
+			headers.Add("DNT", strconv.Itoa(int(*request.Device.DNT)))
+		}
+	}
+
+	if displayManager := getDisplayManager(request.Imp); displayManager != "" {
+		headers.Add("X-Display-Manager", displayManager)
+	}
+	if clickBrowser := getClickBrowser(request.Imp); clickBrowser != nil {
+		headers.Add("X-Click-Browser", strconv.Itoa(int(*clickBrowser)))
+	}
+	if position := getAdPosition(request.Imp); position != nil {
+		headers.Add("X-Ad-Position", strconv.Itoa(int(*position)))
+	}
+	if plcmt := getVideoPlcmt(request.Imp); plcmt != 0 {
+		headers.Add("X-Video-Placement", strconv.Itoa(int(plcmt)))
+	}
+	if language := getLanguage(request); language != "" {
+		headers.Add("Accept-Language", language)
+	}
+	if request.AT != 0 {
+		headers.Add("X-Auction-Type", strconv.FormatInt(request.AT, 10))
+	}
+	if request.Source != nil && request.Source.FD != nil {
+		headers.Add("X-Final-Decision", strconv.Itoa(int(*request.Source.FD)))
+	}
+	if isCOPPA(request) {
+		headers.Add("X-COPPA", "1")
+	}
+	if content := getContent(request); content != nil {
+		if content.Genre != "" {
+			headers.Add("X-Content-Genre", content.Genre)
+		}
+		if len(content.Cat) > 0 {
+			headers.Add("X-Content-Cat", strings.Join(content.Cat, ","))
+		}
+	}
+	if request.Test == 1 && request.User != nil && len(request.User.Data) > 0 {
+		headers.Add("X-User-Segment-Count", strconv.Itoa(countUserSegments(request.User.Data)))
+	}
+	if isDebug(request) {
+		headers.Add("X-Debug", "1")
+	}
+	return headers
+}
+
+// isDebug reports whether the auction runs in debug, which the core enables with either test=1 or
+// ext.prebid.debug.
+func isDebug(request *openrtb2.BidRequest) bool {
+	if request.Test == 1 {
+		return true
+	}
+	debug, err := jsonparser.GetBoolean(request.Ext, "prebid", "debug")
+	return err == nil && debug
+}
+
+// isCOPPA reports whether the request is subject to COPPA, for which mocktioneer returns
+// non-personalized creatives.
+func isCOPPA(request *openrtb2.BidRequest) bool {
+	return request.Regs != nil && request.Regs.COPPA == 1
+}
+
+// getLanguage returns the device's language, falling back to the language of the content.
+func getLanguage(request *openrtb2.BidRequest) string {
+	if request.Device != nil && request.Device.Language != "" {
+		return request.Device.Language
+	}
+	if content := getContent(request); content != nil {
+		return content.Language
+	}
+	return ""
+}
+
+// getContent returns the site's, app's or dooh's content.
+func getContent(request *openrtb2.BidRequest) *openrtb2.Content {
+	switch {
+	case request.Site != nil:
+		return request.Site.Content
+	case request.App != nil:
+		return request.App.Content
+	case request.DOOH != nil:
+		return request.DOOH.Content
+	}
+	return nil
+}
+
+// getAdPosition returns the first imp's banner position, e.g. 1 for above the fold and 3 for below.
+func getAdPosition(imps []openrtb2.Imp) *adcom1.PlacementPosition {
+	for _, imp := range imps {
+		if imp.Banner != nil && imp.Banner.Pos != nil {
+			return imp.Banner.Pos
+		}
+	}
+	return nil
+}
+
+// getVideoPlcmt returns the first video imp's placement as an OpenRTB 2.6 plcmt. A 2.5 placement is
+// mapped to the closest plcmt: in-stream stays in-stream, interstitial stays interstitial and the
+// other outstream placements become standalone, no content.
+func getVideoPlcmt(imps []openrtb2.Imp) adcom1.VideoPlcmtSubtype {
+	for _, imp := range imps {
+		if imp.Video == nil {
+			continue
+		}
+		if imp.Video.Plcmt != 0 {
+			return imp.Video.Plcmt
+		}
+		switch imp.Video.Placement {
+		case 0:
+			continue
+		case adcom1.VideoPlacementInStream:
+			return adcom1.VideoPlcmtInstream
+		case adcom1.VideoPlacementAlwaysVisible:
+			return adcom1.VideoPlcmtInterstitial
+		default:
+			return adcom1.VideoPlcmtNoContent
+		}
+	}
+	return 0
+}
+
+// getClickBrowser returns the first imp's clickbrowser, 0 for embedded and 1 for native.
+func getClickBrowser(imps []openrtb2.Imp) *int8 {
+	for _, imp := range imps {
+		if imp.ClickBrowser != nil {
+			return imp.ClickBrowser
+		}
+	}
+	return nil
+}
+
+// countUserSegments returns the number of segments across the user.data providers.
+func countUserSegments(data []openrtb2.Data) int {
+	var count int
+	for _, provider := range data {
+		count += len(provider.Segment)
+	}
+	return count
+}
+
+// getDisplayManager returns the first imp's display manager as "name/version", or just the name
+// when the imp has no version.
+func getDisplayManager(imps []openrtb2.Imp) string {
+	for _, imp := range imps {
+		if imp.DisplayManager == "" {
+			continue
+		}
+		if imp.DisplayManagerVer == "" {
+			return imp.DisplayManager
+		}
+		return imp.DisplayManager + "/" + imp.DisplayManagerVer
+	}
+	return ""
+}
+
+func (a *adapter) MakeBids(request *openrtb2.BidRequest, requestData *adapters.RequestData, responseData *adapters.ResponseData) (*adapters.BidderResponse, []error) {
+	if slices.Contains(a.extraInfo.NoBidStatusCodes, responseData.StatusCode) {
+		return a.noBidResponse(request, ""), checkExpectedBidCounts(request, requestData, nil)
+	}
+
+	if !slices.Contains(a.extraInfo.OkStatusCodes, responseData.StatusCode) {
+		err := adapters.CheckResponseStatusCodeForErrors(responseData)
+		if err == nil {
+			// A 200 which okStatusCodes leaves out.
+			err = &errortypes.BadServerResponse{
+				Message: fmt.Sprintf("Unexpected status code: %d. Run with request.debug = 1 for more info", responseData.StatusCode),
+			}
+		}
+		if location := getRedirectLocation(responseData); a.extraInfo.FollowRedirects && location != "" {
+			return nil, []error{&errortypes.BadServerResponse{
+				Message: fmt.Sprintf("mocktioneer redirected with status %d to %s, which adapters can't follow; set the endpoint to it instead", responseData.StatusCode, location),
+			}}
+		}
+		if hasForcedError(request, requestData) {
+			return nil, []error{&errortypes.BadServerResponse{
+				Message: fmt.Sprintf("forced error: mocktioneer responded with status %d", responseData.StatusCode),
+			}}
+		}
+		return nil, []error{err}
+	}
+
+	// Some proxies in front of mocktioneer prepend a UTF-8 byte order mark, which isn't valid JSON.
+	// Debug auctions still show the body as received, the core adds it to ext.debug.httpcalls.
+	body := bytes.TrimSpace(bytes.TrimPrefix(responseData.Body, utf8BOM))
+
+	// Mocktioneer sometimes answers a no-bid with an empty 200 instead of a 204.
+	if len(body) == 0 {
+		return a.noBidResponse(request, ""), checkExpectedBidCounts(request, requestData, nil)
+	}
+
+	if exceedsDepth(body, a.extraInfo.MaxResponseDepth) {
+		return nil, []error{&errortypes.BadServerResponse{
+			Message: fmt.Sprintf("response exceeds the maximum JSON depth of %d", a.extraInfo.MaxResponseDepth),
+		}}
+	}
+
+	var bidResp openrtb2.BidResponse
+	if err := jsonutil.Unmarshal(body, &bidResp); err != nil {
+		return nil, []error{&errortypes.BadServerResponse{
+			Message: describeJSONError(body, err),
+		}}
+	}
+
+	var errs []error
+	debug := isDebug(request)
+	if debug {
+		errs = append(errs, describeNestedCalls(bidResp.Ext)...)
+	}
+	errs = append(errs, describeNoBidReasons(bidResp.Ext)...)
+	// Mocktioneer echoes the request id, so a different one means responses got crossed.
+	if bidResp.ID != request.ID {
+		errs = append(errs, &errortypes.Warning{
+			Message: fmt.Sprintf("response id %q doesn't match the request id %q", bidResp.ID, request.ID),
+		})
+	}
+
+	if len(bidResp.SeatBid) == 0 {
+		return a.noBidResponse(request, bidResp.Cur), append(errs, checkExpectedBidCounts(request, requestData, nil)...)
+	}
+
+	imps := make(map[string]*openrtb2.Imp, len(request.Imp))
+	for i := range request.Imp {
+		imps[request.Imp[i].ID] = &request.Imp[i]
+	}
+
+	var duplicates map[string][]string
+	if a.extraInfo.DedupeImps {
+		duplicates = findDuplicateImps(request.Imp)
+	}
+
+	var truncated int
+	soleImpID := getSoleImpID(request, requestData, duplicates)
+	br := adapters.NewBidderResponseWithBidsCapacity(len(request.Imp))
+	br.Currency = bidResp.Cur
+	if br.Currency == "" && len(request.Cur) == 0 {
+		br.Currency = a.extraInfo.DefaultCurrency
+	}
+	responseCurrency := br.Currency
+	if responseCurrency == "" {
+		responseCurrency = "USD"
+	}
+	impParams := getImpParams(request)
+
+	for _, seatBid := range bidResp.SeatBid {
+		if reason := checkSeat(request, seatBid.Seat); reason != "" {
+			for _, bid := range seatBid.Bid {
+				errs = append(errs, &errortypes.Warning{
+					Message: fmt.Sprintf("bid %s dropped: %s", bid.ID, reason),
+				})
+			}
+			continue
+		}
+
+		seatType, err := getSeatBidType(&seatBid)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if seatType == "" {
+			seatType = a.extraInfo.SeatMediaTypes[seatBid.Seat]
+		}
+
+		for i := range seatBid.Bid {
+			bid := &seatBid.Bid[i]
+			if bid.ImpID == "" && soleImpID != "" {
+				bid.ImpID = soleImpID
+			}
+			imp := imps[bid.ImpID]
+
+			if isPrivateAuction(imp) && bid.DealID == "" {
+				errs = append(errs, &errortypes.Warning{
+					Message: fmt.Sprintf("bid %s dropped: imp %s is a private auction and the bid has no dealid", bid.ID, bid.ImpID),
+				})
+				continue
+			}
+
+			if bid.Price < 0 {
+				errs = append(errs, &errortypes.Warning{
+					Message: fmt.Sprintf("bid %s dropped: negative price %v", bid.ID, bid.Price),
+				})
+				continue
+			}
+
+			if domain := a.findBlockedAdomain(bid); domain != "" {
+				errs = append(errs, &errortypes.Warning{
+					Message: fmt.Sprintf("bid %s dropped: adomain %s is blocked", bid.ID, domain),
+				})
+				continue
+			}
+
+			params := impParams[bid.ImpID]
+			if params != nil && params.Cur != "" && isValidCurrency(params.Cur) {
+				if err := convertImpBid(request, bid, params.Cur, responseCurrency); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+			}
+
+			bidExt := parseBidExt(bid)
+
+			bidType, err := getBidType(bid, bidExt, seatType, imp)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if err := a.clampBidPrice(bid, bidType); err != nil && debug {
+				errs = append(errs, err)
+			}
+			if err := checkFloor(request, bid, imp, responseCurrency); err != nil {
+				errs = append(errs, err)
+			}
+
+			if bidType == openrtb_ext.BidTypeBanner {
+				if err := fillBannerSize(bid, imp); err != nil {
+					errs = append(errs, err)
+				}
+			}
+
+			// The imp's expiry is a caching hint for the bid as well.
+			if bid.Exp == 0 && imp != nil && imp.Exp > 0 {
+				bid.Exp = imp.Exp
+			}
+
+			errs = append(errs, validateEvents(bid, bidExt)...)
+			if debug {
+				errs = append(errs, describeStoredRequestAttributes(bid)...)
+			}
+			if bid.BURL != "" && !isValidURL(bid.BURL) {
+				errs = append(errs, &errortypes.Warning{
+					Message: fmt.Sprintf("bid %s has a malformed burl: %s", bid.ID, bid.BURL),
+				})
+			}
+			if err := validateSKAdN(bid); err != nil {
+				errs = append(errs, err)
+			}
+			if params != nil && params.WinNotice != "" && bid.NURL != params.WinNotice {
+				errs = append(errs, &errortypes.Warning{
+					Message: fmt.Sprintf("bid %s has the nurl %q instead of the imp's winNotice %s", bid.ID, bid.NURL, params.WinNotice),
+				})
+			}
+			if err := validateVideoProtocol(bid, bidType, imp); err != nil {
+				errs = append(errs, err)
+			}
+			errs = append(errs, truncateTargetingKeys(bid, bidExt, a.extraInfo.MaxTargetingKeyLength)...)
+
+			if a.extraInfo.MaxBidsPerResponse > 0 && len(br.Bids) >= a.extraInfo.MaxBidsPerResponse {
+				truncated++
+				continue
+			}
+
+			if bid.ID == "" && (bidExt == nil || bidExt.Prebid == nil || bidExt.Prebid.BidId == "") {
+				if err := a.setGeneratedBidID(bid); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if a.extraInfo.CridPrefix != "" {
+				if err := a.prefixCrID(bid); err != nil {
+					errs = append(errs, err)
+				}
+			}
+
+			if bidType != "" && (bidExt == nil || bidExt.Prebid == nil || bidExt.Prebid.Meta == nil || bidExt.Prebid.Meta.MediaType == "") {
+				if err := setBidExt(bid, []byte(strconv.Quote(string(bidType))), "prebid", "meta", "mediaType"); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if bidExt == nil || bidExt.Prebid == nil || bidExt.Prebid.Meta == nil || bidExt.Prebid.Meta.NetworkID == 0 {
+				if err := setMetaNetworkID(bid); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if err := moveEchoedParams(bid); err != nil {
+				errs = append(errs, err)
+			}
+			if err := setTagID(bid, imp); err != nil {
+				errs = append(errs, err)
+			}
+			// Companion bids stay banner bids, so they're marked apart from meta.mediaType.
+			if isCompanionBid(bidType, imp) {
+				if err := setBidExt(bid, []byte("true"), string(openrtb_ext.BidderMocktioneer), "companion"); err != nil {
+					errs = append(errs, err)
+				}
+			}
+
+			// The seat is passed on so the core can validate alternate bidder codes. An empty seat is
+			// left for the core to default to the bidder name.
+			br.Bids = append(br.Bids, &adapters.TypedBid{
+				Bid:          bid,
+				BidType:      bidType,
+				BidVideo:     getBidVideo(bid, bidExt, bidType),
+				DealPriority: getDealPriority(bidExt),
+				Seat:         openrtb_ext.BidderName(seatBid.Seat),
+			})
+		}
+	}
+
+	if a.extraInfo.DedupeImps {
+		var copyErrs []error
+		br.Bids, copyErrs = a.copyBidsToDuplicateImps(br.Bids, duplicates)
+		errs = append(errs, copyErrs...)
+		if a.extraInfo.MaxBidsPerResponse > 0 && len(br.Bids) > a.extraInfo.MaxBidsPerResponse {
+			truncated += len(br.Bids) - a.extraInfo.MaxBidsPerResponse
+			br.Bids = br.Bids[:a.extraInfo.MaxBidsPerResponse]
+		}
+	}
+	if a.extraInfo.TopBidPerImpOnly {
+		var dropped int
+		if br.Bids, dropped = keepTopBidPerImp(br.Bids); dropped > 0 {
+			errs = append(errs, &errortypes.Warning{
+				Message: fmt.Sprintf("%d bids dropped: only the top bid of each imp is kept", dropped),
+			})
+		}
+	}
+	if a.extraInfo.OrderBidsByImp {
+		sortBidsByImp(request, br.Bids)
+	}
+
+	if truncated > 0 {
+		errs = append(errs, &errortypes.Warning{
+			Message: fmt.Sprintf("%d bids dropped: the response exceeded the limit of %d bids", truncated, a.extraInfo.MaxBidsPerResponse),
+		})
+	}
+	errs = append(errs, checkExpectedBidCounts(request, requestData, br.Bids)...)
+
+	if a.extraInfo.ConvertResponseCurrency {
+		if err := convertBidderResponse(request, br); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if a.extraInfo.ResolveAdmMacros {
+		resolveAuctionMacros(br, bidResp.ID)
+	}
+	return br, errs
+}
+
+// resolveAuctionMacros replaces the auction macros in each bid's adm and nurl. It runs after any
+// currency conversion so the price and currency match the returned bid. Unknown macros are left
+// intact.
+func resolveAuctionMacros(br *adapters.BidderResponse, auctionID string) {
+	cur := br.Currency
+	if cur == "" {
+		cur = "USD"
+	}
+
+	for _, typedBid := range br.Bids {
+		bid := typedBid.Bid
+		replacer := strings.NewReplacer(
+			"${AUCTION_ID}", auctionID,
+			"${AUCTION_IMP_ID}", bid.ImpID,
+			"${AUCTION_AD_ID}", bid.AdID,
+			"${AUCTION_PRICE}", strconv.FormatFloat(bid.Price, 'f', -1, 64),
+			"${AUCTION_CURRENCY}", cur,
+		)
+		bid.AdM = replacer.Replace(bid.AdM)
+		bid.NURL = replacer.Replace(bid.NURL)
+	}
+}
+
+// findDuplicateImps returns the ids of the imps identical to an earlier imp but for their id, keyed
+// by the id of that first imp.
+func findDuplicateImps(imps []openrtb2.Imp) map[string][]string {
+	duplicates := make(map[string][]string)
+	firstIDs := make(map[string]string, len(imps))
+	for _, imp := range imps {
+		id := imp.ID
+		imp.ID = ""
+		key, err := jsonutil.Marshal(imp)
+		if err != nil {
+			continue
+		}
+		if firstID, ok := firstIDs[string(key)]; ok {
+			duplicates[firstID] = append(duplicates[firstID], id)
+		} else {
+			firstIDs[string(key)] = id
+		}
+	}
+	return duplicates
+}
+
+// copyBidsToDuplicateImps adds a copy of each bid for every duplicate of its imp. The copy of a bid
+// with an id gets the id suffixed with its imp id, and the copy of a bid without one a generated
+// bid.ext.prebid.bidid of its own.
+func (a *adapter) copyBidsToDuplicateImps(bids []*adapters.TypedBid, duplicates map[string][]string) ([]*adapters.TypedBid, []error) {
+	var errs []error
+	for _, typedBid := range bids {
+		for _, impID := range duplicates[typedBid.Bid.ImpID] {
+			bid := *typedBid.Bid
+			bid.ImpID = impID
+			bid.Ext = slices.Clone(typedBid.Bid.Ext)
+			if bid.ID != "" {
+				bid.ID = typedBid.Bid.ID + "-" + impID
+			} else if err := a.setGeneratedBidID(&bid); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			bidCopy := *typedBid
+			bidCopy.Bid = &bid
+			bids = append(bids, &bidCopy)
+		}
+	}
+	return bids, errs
+}
+
+// keepTopBidPerImp filters the bids down to the highest-priced one of each imp, keeping the order
+// of the response. It returns the number of bids dropped.
+func keepTopBidPerImp(bids []*adapters.TypedBid) ([]*adapters.TypedBid, int) {
+	top := make(map[string]*adapters.TypedBid, len(bids))
+	for _, typedBid := range bids {
+		if best, ok := top[typedBid.Bid.ImpID]; !ok || typedBid.Bid.Price > best.Bid.Price {
+			top[typedBid.Bid.ImpID] = typedBid
+		}
+	}
+
+	kept := make([]*adapters.TypedBid, 0, len(top))
+	for _, typedBid := range bids {
+		if top[typedBid.Bid.ImpID] == typedBid {
+			kept = append(kept, typedBid)
+		}
+	}
+	return kept, len(bids) - len(kept)
+}
+
+// sortBidsByImp sorts bids by the position of their imp in the request and then by descending
+// price. Bids for unknown imps go last.
+func sortBidsByImp(request *openrtb2.BidRequest, bids []*adapters.TypedBid) {
+	impOrder := make(map[string]int, len(request.Imp))
+	for i, imp := range request.Imp {
+		impOrder[imp.ID] = i
+	}
+	position := func(bid *adapters.TypedBid) int {
+		if i, ok := impOrder[bid.Bid.ImpID]; ok {
+			return i
+		}
+		return len(request.Imp)
+	}
+
+	sort.SliceStable(bids, func(i, j int) bool {
+		if pi, pj := position(bids[i]), position(bids[j]); pi != pj {
+			return pi < pj
+		}
+		return bids[i].Bid.Price > bids[j].Bid.Price
+	})
+}
+
+// hasForcedError reports whether any imp sent in the request set the forceError param.
+func hasForcedError(request *openrtb2.BidRequest, requestData *adapters.RequestData) bool {
+	for i := range request.Imp {
+		imp := &request.Imp[i]
+		if len(requestData.ImpIDs) > 0 && !slices.Contains(requestData.ImpIDs, imp.ID) {
+			continue
+		}
+		if impExt, err := parseImpExt(imp); err == nil && impExt.ForceError {
+			return true
+		}
+	}
+	return false
+}
+
+// describeStoredRequestAttributes reports the stored request attributes mocktioneer echoes in
+// bid.ext.prebid.storedrequestattributes, to check stored requests were resolved as expected. The
+// ext itself is returned with the bid untouched.
+func describeStoredRequestAttributes(bid *openrtb2.Bid) []error {
+	attributes, _, _, err := jsonparser.Get(bid.Ext, "prebid", "storedrequestattributes")
+	if err != nil {
+		return nil
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, attributes); err != nil {
+		compacted.Reset()
+		compacted.Write(attributes)
+	}
+	return []error{&errortypes.Warning{
+		Message: fmt.Sprintf("bid %s: mocktioneer applied the stored request attributes %s", bid.ID, compacted.String()),
+	}}
+}
+
+// describeNestedCalls reports the upstream calls mocktioneer made itself, which it lists in the
+// response ext.debug.httpcalls when the auction runs in debug. Adapters have no debug output of
+// their own, so each call becomes a warning in the auction's debug info.
+func describeNestedCalls(responseExt json.RawMessage) []error {
+	if len(responseExt) == 0 {
+		return nil
+	}
+
+	var ext struct {
+		Debug *openrtb_ext.ExtResponseDebug `json:"debug"`
+	}
+	if err := jsonutil.Unmarshal(responseExt, &ext); err != nil {
+		return []error{&errortypes.Warning{
+			Message: fmt.Sprintf("unable to read the mocktioneer debug info: %v", err),
+		}}
+	}
+	if ext.Debug == nil || len(ext.Debug.HttpCalls) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(ext.Debug.HttpCalls))
+	for name := range ext.Debug.HttpCalls {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		for _, call := range ext.Debug.HttpCalls[openrtb_ext.BidderName(name)] {
+			if call == nil {
+				continue
+			}
+			errs = append(errs, &errortypes.Warning{
+				Message: fmt.Sprintf("mocktioneer upstream call %s: %s responded with status %d: %s", name, call.Uri, call.Status, call.ResponseBody),
+			})
+		}
+	}
+	return errs
+}
+
+// describeNoBidReasons reports why mocktioneer didn't bid on some imps, which it lists in the
+// response ext.prebid.nobid keyed by imp id.
+func describeNoBidReasons(responseExt json.RawMessage) []error {
+	value, dataType, _, err := jsonparser.Get(responseExt, "prebid", "nobid")
+	if err != nil || dataType == jsonparser.Null {
+		return nil
+	}
+
+	var reasons map[string]string
+	if err := jsonutil.Unmarshal(value, &reasons); err != nil {
+		return []error{&errortypes.Warning{
+			Message: fmt.Sprintf("unable to read the mocktioneer no-bid reasons: %v", err),
+		}}
+	}
+
+	impIDs := make([]string, 0, len(reasons))
+	for impID := range reasons {
+		impIDs = append(impIDs, impID)
+	}
+	sort.Strings(impIDs)
+
+	errs := make([]error, 0, len(impIDs))
+	for _, impID := range impIDs {
+		errs = append(errs, &errortypes.Warning{
+			Message: fmt.Sprintf("imp %s: mocktioneer didn't bid: %s", impID, reasons[impID]),
+		})
+	}
+	return errs
+}
+
+// checkExpectedBidCounts warns about imps whose expectBidCount param doesn't match the number of
+// bids returned for them. It's an assertion for tests and never changes the bids. When the imps were
+// split across several requests, only the imps sent in this one are checked.
+func checkExpectedBidCounts(request *openrtb2.BidRequest, requestData *adapters.RequestData, bids []*adapters.TypedBid) []error {
+	var errs []error
+	for i := range request.Imp {
+		imp := &request.Imp[i]
+		if len(requestData.ImpIDs) > 0 && !slices.Contains(requestData.ImpIDs, imp.ID) {
+			continue
+		}
+		impExt, err := parseImpExt(imp)
+		if err != nil || impExt.ExpectBidCount == nil || *impExt.ExpectBidCount < 0 {
+			continue
+		}
+
+		count := 0
+		for _, bid := range bids {
+			if bid.Bid.ImpID == imp.ID {
+				count++
+			}
+		}
+		if count != *impExt.ExpectBidCount {
+			errs = append(errs, &errortypes.Warning{
+				Message: fmt.Sprintf("imp %s: expected %d bids but got %d", imp.ID, *impExt.ExpectBidCount, count),
+			})
+		}
+	}
+	return errs
+}
+
+// checkSeat explains why the request's wseat or bseat lists exclude the seat, or returns "" if
+// the seat may bid. An empty wseat allows every seat. Bids without a seat are the bidder's own.
+func checkSeat(request *openrtb2.BidRequest, seat string) string {
+	if seat == "" {
+		seat = string(openrtb_ext.BidderMocktioneer)
+	}
+	if slices.Contains(request.BSeat, seat) {
+		return fmt.Sprintf("seat %s is in bseat", seat)
+	}
+	if len(request.WSeat) > 0 && !slices.Contains(request.WSeat, seat) {
+		return fmt.Sprintf("seat %s is not in wseat", seat)
+	}
+	return ""
+}
+
+// findBlockedAdomain returns the first of the bid's adomains matching the blockedAdomains option,
+// or "" if none do. A blocked domain blocks its subdomains too.
+func (a *adapter) findBlockedAdomain(bid *openrtb2.Bid) string {
+	for _, adomain := range bid.ADomain {
+		domain := normalizeDomain(adomain)
+		for _, blocked := range a.extraInfo.BlockedAdomains {
+			if blocked != "" && (domain == blocked || strings.HasSuffix(domain, "."+blocked)) {
+				return adomain
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeDomain lowercases a domain and drops any trailing dot so equivalent spellings match.
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+// validateCpmBounds checks the minCpm and maxCpm options are for known media types and that each
+// media type's minimum isn't above its maximum.
+func validateCpmBounds(minCpm, maxCpm map[openrtb_ext.BidType]float64) error {
+	for _, bounds := range []struct {
+		name   string
+		values map[openrtb_ext.BidType]float64
+	}{{"minCpm", minCpm}, {"maxCpm", maxCpm}} {
+		for bidType, value := range bounds.values {
+			if _, err := openrtb_ext.ParseBidType(string(bidType)); err != nil {
+				return fmt.Errorf("%s: %v", bounds.name, err)
+			}
+			if value < 0 {
+				return fmt.Errorf("%s for %s must not be negative", bounds.name, bidType)
+			}
+		}
+	}
+	for bidType, minPrice := range minCpm {
+		if maxPrice, ok := maxCpm[bidType]; ok && minPrice > maxPrice {
+			return fmt.Errorf("minCpm for %s must not be above its maxCpm", bidType)
+		}
+	}
+	return nil
+}
+
+// clampBidPrice raises or lowers the bid's price to the bounds configured for its media type. The
+// returned warning describes the change.
+func (a *adapter) clampBidPrice(bid *openrtb2.Bid, bidType openrtb_ext.BidType) error {
+	if minPrice, ok := a.extraInfo.MinCpm[bidType]; ok && bid.Price < minPrice {
+		price := bid.Price
+		bid.Price = minPrice
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s: price %v raised to the %s minCpm of %v", bid.ID, price, bidType, minPrice),
+		}
+	}
+	if maxPrice, ok := a.extraInfo.MaxCpm[bidType]; ok && bid.Price > maxPrice {
+		price := bid.Price
+		bid.Price = maxPrice
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s: price %v lowered to the %s maxCpm of %v", bid.ID, price, bidType, maxPrice),
+		}
+	}
+	return nil
+}
+
+// validateStatusCodes checks the noBidStatusCodes and okStatusCodes options are HTTP statuses and
+// that no status is in both.
+func validateStatusCodes(noBidStatusCodes, okStatusCodes []int) error {
+	for _, codes := range []struct {
+		name   string
+		values []int
+	}{{"noBidStatusCodes", noBidStatusCodes}, {"okStatusCodes", okStatusCodes}} {
+		for _, code := range codes.values {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("%s: %d is not an HTTP status code", codes.name, code)
+			}
+		}
+	}
+	for _, code := range noBidStatusCodes {
+		if slices.Contains(okStatusCodes, code) {
+			return fmt.Errorf("status code %d can't be in both noBidStatusCodes and okStatusCodes", code)
+		}
+	}
+	return nil
+}
+
+// getRedirectLocation returns the Location header of a 3xx response.
+func getRedirectLocation(responseData *adapters.ResponseData) string {
+	if responseData.StatusCode < http.StatusMultipleChoices || responseData.StatusCode >= http.StatusBadRequest {
+		return ""
+	}
+	return responseData.Headers.Get("Location")
+}
+
+// getSoleImpID returns the imp id of a single-imp request, which is the only imp a bid without an
+// imp id can be for. It's empty for requests with several imps. The duplicate imps collapsed by
+// dedupeImps weren't sent to mocktioneer, so they don't count.
+func getSoleImpID(request *openrtb2.BidRequest, requestData *adapters.RequestData, duplicates map[string][]string) string {
+	impIDs := requestData.ImpIDs
+	if len(impIDs) == 0 {
+		impIDs = openrtb_ext.GetImpIDs(request.Imp)
+	}
+
+	isDuplicate := make(map[string]bool)
+	for _, ids := range duplicates {
+		for _, id := range ids {
+			isDuplicate[id] = true
+		}
+	}
+
+	var soleImpID string
+	for _, impID := range impIDs {
+		if isDuplicate[impID] {
+			continue
+		}
+		if soleImpID != "" {
+			return ""
+		}
+		soleImpID = impID
+	}
+	return soleImpID
+}
+
+// noBidResponse is returned when mocktioneer doesn't bid. By default that's nil, but with
+// emptyResponseOnNoBid set it's an empty response which still carries the auction currency: the
+// response currency if mocktioneer sent one, otherwise the request's first currency or else the
+// default currency.
+func (a *adapter) noBidResponse(request *openrtb2.BidRequest, responseCurrency string) *adapters.BidderResponse {
+	if !a.extraInfo.EmptyResponseOnNoBid {
+		return nil
+	}
+
+	br := adapters.NewBidderResponse()
+	if responseCurrency != "" {
+		br.Currency = responseCurrency
+	} else if len(request.Cur) > 0 {
+		br.Currency = request.Cur[0]
+	} else {
+		br.Currency = a.extraInfo.DefaultCurrency
+	}
+	return br
+}
+
+// checkExpectedEndpoints warns about imps whose expectEndpoint param doesn't match the endpoint
+// their request was sent to. It's an assertion for routing tests and never changes the routing.
+func checkExpectedEndpoints(imps []openrtb2.Imp, endpoint string, impParams map[string]*openrtb_ext.ExtMocktioneer) []error {
+	var errs []error
+	for _, imp := range imps {
+		params := impParams[imp.ID]
+		if params == nil || params.ExpectEndpoint == "" || params.ExpectEndpoint == endpoint {
+			continue
+		}
+		errs = append(errs, &errortypes.Warning{
+			Message: fmt.Sprintf("imp %s was sent to %s instead of the expected endpoint %s", imp.ID, endpoint, params.ExpectEndpoint),
+		})
+	}
+	return errs
+}
+
+// getImpParams returns the params of each imp with a valid ext, by imp id.
+func getImpParams(request *openrtb2.BidRequest) map[string]*openrtb_ext.ExtMocktioneer {
+	params := make(map[string]*openrtb_ext.ExtMocktioneer, len(request.Imp))
+	for i := range request.Imp {
+		if impExt, err := parseImpExt(&request.Imp[i]); err == nil {
+			params[request.Imp[i].ID] = impExt
+		}
+	}
+	return params
+}
+
+// isValidCurrency reports whether the code is a known 3-letter ISO 4217 currency code.
+func isValidCurrency(code string) bool {
+	if len(code) != 3 || strings.ToUpper(code) != code {
+		return false
+	}
+	_, err := xcurrency.ParseISO(code)
+	return err == nil
+}
+
+// convertBidderResponse converts the bid prices to the request's first currency. MakeBids has no
+// access to the core's conversion rates, so the option only works with the custom rates from
+// request.ext.prebid.currency; bids are left in the response currency when no rate is available.
+// The original price isn't recorded, since the core overwrites bid.ext.origbidcpm and origbidcur.
+func convertBidderResponse(request *openrtb2.BidRequest, br *adapters.BidderResponse) error {
+	from := br.Currency
+	if from == "" {
+		from = "USD"
+	}
+	if len(request.Cur) == 0 || strings.EqualFold(from, request.Cur[0]) {
+		return nil
+	}
+	to := request.Cur[0]
+
+	rate, err := getRequestRate(request, from, to)
+	if err != nil {
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("unable to convert bids from %s to %s: %v", from, to, err),
+		}
+	}
+
+	for _, typedBid := range br.Bids {
+		typedBid.Bid.Price = typedBid.Bid.Price * rate
+	}
+	br.Currency = to
+	return nil
+}
+
+// convertImpBid converts the price of a bid for an imp with a cur param, which mocktioneer prices in
+// that currency, to the currency of the rest of the response. A bidder response has a single
+// currency, so bids which can't be converted are dropped.
+func convertImpBid(request *openrtb2.BidRequest, bid *openrtb2.Bid, from, to string) error {
+	if strings.EqualFold(from, to) {
+		return nil
+	}
+	rate, err := getRequestRate(request, from, to)
+	if err != nil {
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s dropped: unable to convert it from the imp's currency %s to %s: %v", bid.ID, from, to, err),
+		}
+	}
+	return convertBidPrice(bid, from, rate)
+}
+
+// getRequestRate returns the conversion rate from the request's ext.prebid.currency rates.
+func getRequestRate(request *openrtb2.BidRequest, from, to string) (float64, error) {
+	var requestExt openrtb_ext.ExtRequest
+	if len(request.Ext) > 0 {
+		if err := jsonutil.Unmarshal(request.Ext, &requestExt); err != nil {
+			return 0, fmt.Errorf("invalid request ext: %v", err)
+		}
+	}
+	if requestExt.Prebid.CurrencyConversions == nil {
+		return 0, errors.New("the request has no currency rates")
+	}
+	return currency.NewRates(requestExt.Prebid.CurrencyConversions.ConversionRates).GetRate(from, to)
+}
+
+// convertBidPrice applies the rate to the bid's price. The first conversion of a bid records its
+// original price and currency in the bid ext.
+func convertBidPrice(bid *openrtb2.Bid, from string, rate float64) error {
+	if _, _, _, err := jsonparser.Get(bid.Ext, openrtb_ext.OriginalBidCpmKey); err != nil {
+		if err := setBidExt(bid, []byte(strconv.FormatFloat(bid.Price, 'f', -1, 64)), openrtb_ext.OriginalBidCpmKey); err != nil {
+			return err
+		}
+		if err := setBidExt(bid, []byte(strconv.Quote(from)), openrtb_ext.OriginalBidCurKey); err != nil {
+			return err
+		}
+	}
+	bid.Price = bid.Price * rate
+	return nil
+}
+
+// setGeneratedBidID gives a bid without any id a generated bid.ext.prebid.bidid, so wins can still
+// be attributed to it.
+func (a *adapter) setGeneratedBidID(bid *openrtb2.Bid) error {
+	bidID, err := a.uuidGenerator.Generate()
+	if err != nil {
+		return &errortypes.BadServerResponse{
+			Message: fmt.Sprintf("unable to generate a bid id for a bid on imp %s: %v", bid.ImpID, err),
+		}
+	}
+	return setBidExt(bid, []byte(strconv.Quote(bidID)), "prebid", "bidid")
+}
+
+// prefixCrID prepends the cridPrefix option to the bid's crid, generating the crid when the bid
+// has none.
+func (a *adapter) prefixCrID(bid *openrtb2.Bid) error {
+	if bid.CrID == "" {
+		crID, err := a.uuidGenerator.Generate()
+		if err != nil {
+			return &errortypes.BadServerResponse{
+				Message: fmt.Sprintf("unable to generate a crid for bid %s: %v", bid.ID, err),
+			}
+		}
+		bid.CrID = crID
+	}
+	bid.CrID = a.extraInfo.CridPrefix + bid.CrID
+	return nil
+}
+
+// setMetaNetworkID copies mocktioneer's bid.ext.networkId to bid.ext.prebid.meta.networkId, where
+// the core expects it.
+func setMetaNetworkID(bid *openrtb2.Bid) error {
+	value, dataType, _, err := jsonparser.Get(bid.Ext, "networkId")
+	if err != nil {
+		return nil
+	}
+
+	networkID, err := strconv.Atoi(string(value))
+	if (dataType != jsonparser.Number && dataType != jsonparser.String) || err != nil || networkID <= 0 {
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s has an invalid networkId %s", bid.ID, value),
+		}
+	}
+	return setBidExt(bid, []byte(strconv.Itoa(networkID)), "prebid", "meta", "networkId")
+}
+
+// setTagID copies the imp's tagid to bid.ext.tagid for reporting, unless mocktioneer set one.
+func setTagID(bid *openrtb2.Bid, imp *openrtb2.Imp) error {
+	if imp == nil || imp.TagID == "" {
+		return nil
+	}
+	if _, _, _, err := jsonparser.Get(bid.Ext, "tagid"); err == nil {
+		return nil
+	}
+	return setBidExt(bid, []byte(strconv.Quote(imp.TagID)), "tagid")
+}
+
+// moveEchoedParams moves the bidder params mocktioneer echoes in bid.ext.mocktioneer.echo to
+// bid.ext.prebid.bidder.mocktioneer, where prebid keeps a bidder's params. An ext.mocktioneer left
+// empty is removed.
+func moveEchoedParams(bid *openrtb2.Bid) error {
+	echo, _, _, err := jsonparser.Get(bid.Ext, string(openrtb_ext.BidderMocktioneer), "echo")
+	if err != nil {
+		return nil
+	}
+	if err := setBidExt(bid, echo, "prebid", "bidder", string(openrtb_ext.BidderMocktioneer)); err != nil {
+		return err
+	}
+
+	bid.Ext = jsonparser.Delete(bid.Ext, string(openrtb_ext.BidderMocktioneer), "echo")
+	if rest, _, _, err := jsonparser.Get(bid.Ext, string(openrtb_ext.BidderMocktioneer)); err == nil && isEmptyObject(rest) {
+		bid.Ext = jsonparser.Delete(bid.Ext, string(openrtb_ext.BidderMocktioneer))
+	}
+	return nil
+}
+
+// isEmptyObject reports whether the JSON is an object without any keys.
+func isEmptyObject(data []byte) bool {
+	empty := true
+	err := jsonparser.ObjectEach(data, func(_ []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+		empty = false
+		return nil
+	})
+	return err == nil && empty
+}
+
+// setBidExt sets the raw JSON value at the given path of bid.ext, creating the ext if the bid has none.
+func setBidExt(bid *openrtb2.Bid, value []byte, keys ...string) error {
+	updated, err := setJSON(bid.Ext, value, keys...)
+	if err != nil {
+		return &errortypes.BadServerResponse{
+			Message: fmt.Sprintf("bid %s: unable to update ext: %v", bid.ID, err),
+		}
+	}
+	bid.Ext = updated
+	return nil
+}
+
+// setJSON returns a copy of the JSON object with the raw value set at the given path. The input is
+// never modified since jsonparser.Set may reuse its backing array, and imp exts are shared with the
+// core's copy of the request.
+func setJSON(data []byte, value []byte, keys ...string) ([]byte, error) {
+	object := []byte("{}")
+	if len(data) > 0 {
+		object = bytes.Clone(data)
+	}
+	return jsonparser.Set(object, value, keys...)
+}
+
+// exceedsDepth reports whether the JSON nests objects and arrays deeper than maxDepth. Brackets
+// inside strings are skipped, so it can run before the body is known to be valid JSON.
+func exceedsDepth(data []byte, maxDepth int) bool {
+	var (
+		depth    int
+		inString bool
+		escaped  bool
+	)
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
+}
+
+// maxBodyExcerptLength caps how much of a malformed response body is quoted in error messages.
+const maxBodyExcerptLength = 256
+
+// describeJSONError explains why the body isn't a valid bid response. The jsonutil error doesn't
+// say where parsing failed, so the body is parsed again with encoding/json for the offset of the
+// first error. Only a truncated excerpt of the body is included to keep large responses out of logs.
+func describeJSONError(body []byte, err error) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "invalid JSON: %v", err)
+
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	stdErr := json.Unmarshal(body, &openrtb2.BidResponse{})
+	switch {
+	case errors.As(stdErr, &syntaxErr):
+		fmt.Fprintf(&message, " at offset %d", syntaxErr.Offset)
+	case errors.As(stdErr, &typeErr):
+		fmt.Fprintf(&message, " at offset %d in field %s", typeErr.Offset, typeErr.Field)
+	}
+
+	excerpt := body
+	if len(excerpt) > maxBodyExcerptLength {
+		excerpt = excerpt[:maxBodyExcerptLength]
+	}
+	fmt.Fprintf(&message, ", body: %s", strings.ToValidUTF8(string(excerpt), ""))
+	if len(body) > maxBodyExcerptLength {
+		fmt.Fprintf(&message, "... (%d bytes)", len(body))
+	}
+	return message.String()
+}
+
+// isPrivateAuction reports whether only deal bids are eligible for the imp.
+func isPrivateAuction(imp *openrtb2.Imp) bool {
+	return imp != nil && imp.PMP != nil && imp.PMP.PrivateAuction == 1
+}
+
+// parseBidExt returns the bid's ext, or nil when the bid has no ext or it isn't in the prebid format.
+func parseBidExt(bid *openrtb2.Bid) *openrtb_ext.ExtBid {
+	if bid.Ext == nil {
+		return nil
+	}
+
+	var bidExt openrtb_ext.ExtBid
+	if err := jsonutil.Unmarshal(bid.Ext, &bidExt); err != nil {
+		return nil
+	}
+	return &bidExt
+}
+
+// validateSKAdN warns about a bid.ext.skadn SKAdNetwork object without a network, campaign or
+// signature. SKAdNetwork 4 signs each of the fidelities instead of the object itself. The bid is
+// kept either way.
+func validateSKAdN(bid *openrtb2.Bid) error {
+	value, _, _, err := jsonparser.Get(bid.Ext, openrtb_ext.SKAdNExtKey)
+	if err != nil {
+		return nil
+	}
+
+	var skadn struct {
+		Network    string          `json:"network"`
+		Campaign   json.RawMessage `json:"campaign"`
+		Signature  string          `json:"signature"`
+		Fidelities []struct {
+			Signature string `json:"signature"`
+		} `json:"fidelities"`
+	}
+	if err := jsonutil.Unmarshal(value, &skadn); err != nil {
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s has a malformed skadn object: %v", bid.ID, err),
+		}
+	}
+
+	signed := skadn.Signature != ""
+	if !signed && len(skadn.Fidelities) > 0 {
+		signed = true
+		for _, fidelity := range skadn.Fidelities {
+			signed = signed && fidelity.Signature != ""
+		}
+	}
+
+	var missing []string
+	if skadn.Network == "" {
+		missing = append(missing, "network")
+	}
+	if len(skadn.Campaign) == 0 || string(skadn.Campaign) == "null" || string(skadn.Campaign) == `""` {
+		missing = append(missing, "campaign")
+	}
+	if !signed {
+		missing = append(missing, "signature")
+	}
+	if len(missing) > 0 {
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s has an incomplete skadn object, missing %s", bid.ID, strings.Join(missing, ", ")),
+		}
+	}
+	return nil
+}
+
+// validateEvents warns about event tracking urls in bid.ext.prebid.events which aren't absolute urls.
+// The bid is kept either way, the core decides what to do with its events.
+func validateEvents(bid *openrtb2.Bid, bidExt *openrtb_ext.ExtBid) []error {
+	if bidExt == nil || bidExt.Prebid == nil || bidExt.Prebid.Events == nil {
+		return nil
+	}
+
+	var errs []error
+	events := []struct{ name, url string }{
+		{"win", bidExt.Prebid.Events.Win},
+		{"imp", bidExt.Prebid.Events.Imp},
+	}
+	for _, event := range events {
+		if event.url != "" && !isValidURL(event.url) {
+			errs = append(errs, &errortypes.Warning{
+				Message: fmt.Sprintf("bid %s has a malformed %s event url: %s", bid.ID, event.name, event.url),
+			})
+		}
+	}
+	return errs
+}
+
+func isValidURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
+
+// truncateTargetingKeys truncates the bid.ext.prebid.targeting keys longer than maxLength with a
+// warning. The key-values are kept, unless a key truncates to a key which is already set: the keys
+// are handled in order and the first value is kept, with a warning about the dropped one.
+func truncateTargetingKeys(bid *openrtb2.Bid, bidExt *openrtb_ext.ExtBid, maxLength int) []error {
+	if bidExt == nil || bidExt.Prebid == nil || len(bidExt.Prebid.Targeting) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(bidExt.Prebid.Targeting))
+	for key := range bidExt.Prebid.Targeting {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	targeting := make(map[string]string, len(keys))
+	for _, key := range keys {
+		truncated := key
+		if len(key) > maxLength {
+			truncated = key[:maxLength]
+			if _, ok := targeting[truncated]; ok {
+				errs = append(errs, &errortypes.Warning{
+					Message: fmt.Sprintf("bid %s: targeting key %s is longer than %d characters and dropped, its truncated key %s is already set", bid.ID, key, maxLength, truncated),
+				})
+				continue
+			}
+			errs = append(errs, &errortypes.Warning{
+				Message: fmt.Sprintf("bid %s: targeting key %s is longer than %d characters, truncated to %s", bid.ID, key, maxLength, truncated),
+			})
+		}
+		targeting[truncated] = bidExt.Prebid.Targeting[key]
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	value, err := jsonutil.Marshal(targeting)
+	if err != nil {
+		return append(errs, err)
+	}
+	if err := setBidExt(bid, value, "prebid", "targeting"); err != nil {
+		return append(errs, err)
+	}
+	return errs
+}
+
+// validateVideoProtocol warns about video bids whose bid.protocol isn't one of the imp's video
+// protocols. Imps which don't restrict protocols and bids which don't declare one aren't checked,
+// and the bid is kept either way.
+func validateVideoProtocol(bid *openrtb2.Bid, bidType openrtb_ext.BidType, imp *openrtb2.Imp) error {
+	if bidType != openrtb_ext.BidTypeVideo || bid.Protocol == 0 || imp == nil || imp.Video == nil || len(imp.Video.Protocols) == 0 {
+		return nil
+	}
+	if slices.Contains(imp.Video.Protocols, bid.Protocol) {
+		return nil
+	}
+	return &errortypes.Warning{
+		Message: fmt.Sprintf("bid %s has protocol %d which imp %s doesn't allow", bid.ID, bid.Protocol, imp.ID),
+	}
+}
+
+// getBidVideo returns the video details the core needs to build ad pods. The duration comes from
+// bid.dur, falling back to bid.ext.prebid.video.duration for bids which only set the ext.
+func getBidVideo(bid *openrtb2.Bid, bidExt *openrtb_ext.ExtBid, bidType openrtb_ext.BidType) *openrtb_ext.ExtBidPrebidVideo {
+	if bidType != openrtb_ext.BidTypeVideo {
+		return nil
+	}
+
+	var video openrtb_ext.ExtBidPrebidVideo
+	if bidExt != nil && bidExt.Prebid != nil && bidExt.Prebid.Video != nil {
+		video = *bidExt.Prebid.Video
+	}
+	if bid.Dur > 0 {
+		video.Duration = int(bid.Dur)
+	}
+
+	if video.Duration == 0 && video.PrimaryCategory == "" {
+		return nil
+	}
+	return &video
+}
+
+// isCompanionBid reports whether a banner bid is for a companion ad of its imp: the imp is a video
+// imp with companion ads and no banner of its own the bid could be for.
+func isCompanionBid(bidType openrtb_ext.BidType, imp *openrtb2.Imp) bool {
+	return bidType == openrtb_ext.BidTypeBanner && imp != nil && imp.Banner == nil && imp.Video != nil && len(imp.Video.CompanionAd) > 0
+}
+
+// fillBannerSize sets the size of a banner bid without one to its imp's, which is only known when
+// the imp has a single size. For imps with several formats the bid is left unsized with a warning.
+func fillBannerSize(bid *openrtb2.Bid, imp *openrtb2.Imp) error {
+	if bid.W != 0 || bid.H != 0 || imp == nil || imp.Banner == nil {
+		return nil
+	}
+
+	banner := imp.Banner
+	switch {
+	case banner.W != nil && banner.H != nil && *banner.W > 0 && *banner.H > 0:
+		bid.W, bid.H = *banner.W, *banner.H
+	case len(banner.Format) == 1:
+		bid.W, bid.H = banner.Format[0].W, banner.Format[0].H
+	case len(banner.Format) > 1:
+		return &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s has no size and imp %s has several formats, leaving it unsized", bid.ID, bid.ImpID),
+		}
+	}
+	return nil
+}
+
+// getDealPriority returns the bid.ext.prebid.dealpriority the core's deal prioritization ranks
+// deal bids by, 0 when the bid has none.
+func getDealPriority(bidExt *openrtb_ext.ExtBid) int {
+	if bidExt == nil || bidExt.Prebid == nil {
+		return 0
+	}
+	return bidExt.Prebid.DealPriority
+}
+
+// getSeatBidType returns the seat-level seatbid.ext.prebid.type, which applies to every bid in the
+// seat that doesn't carry its own type. An unsupported type is ignored with a warning.
+func getSeatBidType(seatBid *openrtb2.SeatBid) (openrtb_ext.BidType, error) {
+	seatType, err := jsonparser.GetString(seatBid.Ext, "prebid", "type")
+	if err != nil || seatType == "" {
+		return "", nil
+	}
+
+	bidType, err := openrtb_ext.ParseBidType(seatType)
+	if err != nil {
+		return "", &errortypes.Warning{
+			Message: fmt.Sprintf("seat %s has an unsupported type %s, ignoring it", seatBid.Seat, seatType),
+		}
+	}
+	return bidType, nil
+}
+
+// getBidType resolves the bid's media type from bid.mtype, then bid.ext.prebid.type, then the
+// seat-level type or the seatMediaTypes option, then the matching imp. Bids for an unknown imp
+// default to banner with a warning, bids for a multiformat imp default to banner silently.
+func getBidType(bid *openrtb2.Bid, bidExt *openrtb_ext.ExtBid, seatType openrtb_ext.BidType, imp *openrtb2.Imp) (openrtb_ext.BidType, error) {
+	switch bid.MType {
+	case openrtb2.MarkupBanner:
+		return openrtb_ext.BidTypeBanner, nil
+	case openrtb2.MarkupVideo:
+		return openrtb_ext.BidTypeVideo, nil
+	case openrtb2.MarkupAudio:
+		return openrtb_ext.BidTypeAudio, nil
+	case openrtb2.MarkupNative:
+		return openrtb_ext.BidTypeNative, nil
+	}
+
+	if bidExt != nil && bidExt.Prebid != nil && bidExt.Prebid.Type != "" {
+		return bidExt.Prebid.Type, nil
+	}
+
+	if seatType != "" {
+		return seatType, nil
+	}
+
+	if imp == nil {
+		return openrtb_ext.BidTypeBanner, &errortypes.Warning{
+			Message: fmt.Sprintf("bid %s references unknown imp %s, defaulting to banner", bid.ID, bid.ImpID),
+		}
+	}
+
+	if bidType, ok := mediaTypeForImp(imp); ok {
+		return bidType, nil
+	}
+	return openrtb_ext.BidTypeBanner, nil
+}
+
+// mediaTypeForImp returns the imp's mediaType param, or infers the media type from the imp. It only
+// infers imps that declare a single media type, since a multiformat imp doesn't say which format the
+// bid was made for.
+func mediaTypeForImp(imp *openrtb2.Imp) (openrtb_ext.BidType, bool) {
+	if impExt, err := parseImpExt(imp); err == nil && isSupportedMediaType(impExt.MediaType) {
+		return openrtb_ext.BidType(impExt.MediaType), true
+	}
+
+	var (
+		bidType openrtb_ext.BidType
+		count   int
+	)
+	if imp.Banner != nil {
+		bidType = openrtb_ext.BidTypeBanner
+		count++
+	}
+	if imp.Video != nil {
+		bidType = openrtb_ext.BidTypeVideo
+		count++
+	}
+	if imp.Native != nil {
+		bidType = openrtb_ext.BidTypeNative
+		count++
+	}
+	if imp.Audio != nil {
+		bidType = openrtb_ext.BidTypeAudio
+		count++
+	}
+	return bidType, count == 1
+}
@@ -0,0 +1,3434 @@
+package mocktioneer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prebid/openrtb/v20/adcom1"
+	"github.com/prebid/openrtb/v20/openrtb2"
+	"github.com/prebid/prebid-server/v3/adapters"
+	"github.com/prebid/prebid-server/v3/adapters/adapterstest"
+	"github.com/prebid/prebid-server/v3/config"
+	"github.com/prebid/prebid-server/v3/errortypes"
+	"github.com/prebid/prebid-server/v3/openrtb_ext"
+	"github.com/prebid/prebid-server/v3/util/ptrutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonSamples(t *testing.T) {
+	bidder, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint: "https://mocktioneer.test/openrtb2/auction"}, config.Server{ExternalUrl: "http://hosturl.com", GvlID: 1, DataCenter: "2"})
+
+	if buildErr != nil {
+		t.Fatalf("Builder returned unexpected error %v", buildErr)
+	}
+
+	adapterstest.RunJSONBidderTest(t, "mocktioneertest", bidder)
+}
+
+func TestEndpointTemplateMalformed(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint: "{{Malformed}}"}, config.Server{ExternalUrl: "http://hosturl.com", GvlID: 1, DataCenter: "2"})
+
+	assert.Error(t, buildErr)
+}
+
+func TestInvalidExtraInfo(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: "not-json",
+	}, config.Server{})
+
+	assert.Error(t, buildErr)
+}
+
+func TestRequestTemplateMalformed(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"requestTemplate": "{{.ID"}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, `unable to parse request template: template: requestTemplate:1: unclosed action`)
+}
+
+func TestRequestTemplate(t *testing.T) {
+	tests := []struct {
+		name          string
+		template      string
+		expectedBody  string
+		expectedError string
+	}{
+		{
+			name:         "reshapes-request",
+			template:     `{"auction":"{{.ID}}","imps":{{json .Imp}}}`,
+			expectedBody: `{"auction":"test-request-id","imps":[{"id":"test-imp-id","banner":{"w":300,"h":250}}]}`,
+		},
+		{
+			name:          "invalid-json-output",
+			template:      `{{.ID}}`,
+			expectedError: "request template did not render valid JSON",
+		},
+		{
+			name:          "execution-failure",
+			template:      `{{.Missing}}`,
+			expectedError: `unable to execute request template: template: requestTemplate:1:2: executing "requestTemplate" at <.Missing>: can't evaluate field Missing in type *openrtb2.BidRequest`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, `{"requestTemplate": `+quote(test.template)+`}`)
+
+			request := &openrtb2.BidRequest{
+				ID:  "test-request-id",
+				Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{W: ptrutil.ToPtr(int64(300)), H: ptrutil.ToPtr(int64(250))}}},
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			if test.expectedError != "" {
+				require.Len(t, errs, 1)
+				assert.EqualError(t, errs[0], test.expectedError)
+				assert.Empty(t, requests)
+				return
+			}
+			require.Empty(t, errs)
+			require.Len(t, requests, 1)
+			assert.JSONEq(t, test.expectedBody, string(requests[0].Body))
+		})
+	}
+}
+
+func buildTestBidder(t *testing.T, extraInfo string) adapters.Bidder {
+	t.Helper()
+
+	bidder, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: extraInfo,
+	}, config.Server{ExternalUrl: "http://hosturl.com", GvlID: 1, DataCenter: "2"})
+	require.NoError(t, buildErr)
+
+	return bidder
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func TestRequireHTTPS(t *testing.T) {
+	tests := []struct {
+		name          string
+		endpoint      string
+		expectedError string
+	}{
+		{
+			name:     "https",
+			endpoint: "https://mocktioneer.test/openrtb2/auction",
+		},
+		{
+			name:          "http",
+			endpoint:      "http://mocktioneer.test/openrtb2/auction",
+			expectedError: "endpoint must use https: http://mocktioneer.test/openrtb2/auction",
+		},
+		{
+			name:          "scheme-from-macro",
+			endpoint:      "{{.Host}}/openrtb2/auction",
+			expectedError: "endpoint must use https: /openrtb2/auction",
+		},
+		{
+			name:          "no-scheme",
+			endpoint:      "mocktioneer.test/openrtb2/auction",
+			expectedError: "endpoint must use https: mocktioneer.test/openrtb2/auction",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+				Endpoint:         test.endpoint,
+				ExtraAdapterInfo: `{"requireHTTPS": true}`,
+			}, config.Server{})
+
+			if test.expectedError != "" {
+				assert.EqualError(t, buildErr, test.expectedError)
+			} else {
+				assert.NoError(t, buildErr)
+			}
+		})
+	}
+}
+
+func TestHTTPAllowedByDefault(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint: "http://mocktioneer.test/openrtb2/auction",
+	}, config.Server{})
+
+	assert.NoError(t, buildErr)
+}
+
+func TestConvertResponseCurrency(t *testing.T) {
+	tests := []struct {
+		name             string
+		extraInfo        string
+		requestExt       string
+		expectedCurrency string
+		expectedPrice    float64
+		expectedBidExt   string
+		expectedErrors   []string
+	}{
+		{
+			name:             "converts-to-request-currency",
+			extraInfo:        `{"convertResponseCurrency": true}`,
+			requestExt:       `{"prebid":{"currency":{"rates":{"EUR":{"USD":1.25}}}}}`,
+			expectedCurrency: "USD",
+			expectedPrice:    2.5,
+			expectedBidExt:   `{"prebid":{"meta":{"mediaType":"banner"}}}`,
+		},
+		{
+			name:             "no-rates",
+			extraInfo:        `{"convertResponseCurrency": true}`,
+			expectedCurrency: "EUR",
+			expectedPrice:    2,
+			expectedBidExt:   `{"prebid":{"meta":{"mediaType":"banner"}}}`,
+			expectedErrors:   []string{"unable to convert bids from EUR to USD: the request has no currency rates"},
+		},
+		{
+			name:             "disabled",
+			requestExt:       `{"prebid":{"currency":{"rates":{"EUR":{"USD":1.25}}}}}`,
+			expectedCurrency: "EUR",
+			expectedPrice:    2,
+			expectedBidExt:   `{"prebid":{"meta":{"mediaType":"banner"}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID:  "test-request-id",
+				Cur: []string{"USD"},
+				Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}},
+			}
+			if test.requestExt != "" {
+				request.Ext = json.RawMessage(test.requestExt)
+			}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","cur":"EUR","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":2}]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.Equal(t, test.expectedCurrency, bidderResponse.Currency)
+			assert.Equal(t, test.expectedPrice, bidderResponse.Bids[0].Bid.Price)
+			assert.JSONEq(t, test.expectedBidExt, string(bidderResponse.Bids[0].Bid.Ext))
+		})
+	}
+}
+
+func assertErrorMessages(t *testing.T, expected []string, actual []error) {
+	t.Helper()
+
+	messages := make([]string, 0, len(actual))
+	for _, err := range actual {
+		messages = append(messages, err.Error())
+	}
+	if len(expected) == 0 {
+		assert.Empty(t, messages)
+		return
+	}
+	assert.Equal(t, expected, messages)
+}
+
+func TestInvalidJSONResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedContains []string
+		expectedMaxLen   int
+	}{
+		{
+			name:             "syntax-error",
+			body:             `{"id": "test-request-id", "seatbid": [}`,
+			expectedContains: []string{"invalid JSON: ", " at offset ", `, body: {"id": "test-request-id", "seatbid": [}`},
+		},
+		{
+			name:             "type-error",
+			body:             `{"id": "test-request-id", "seatbid": "none"}`,
+			expectedContains: []string{"invalid JSON: ", " at offset ", " in field seatbid"},
+		},
+		{
+			name:             "truncates-large-body",
+			body:             `{"id": "` + strings.Repeat("a", 10000) + `"`,
+			expectedContains: []string{"... (10009 bytes)"},
+			expectedMaxLen:   1024,
+		},
+	}
+
+	bidder := buildTestBidder(t, "")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id"}}}
+			response := &adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(test.body)}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assert.Nil(t, bidderResponse)
+			require.Len(t, errs, 1)
+			assert.IsType(t, &errortypes.BadServerResponse{}, errs[0])
+			for _, expected := range test.expectedContains {
+				assert.Contains(t, errs[0].Error(), expected)
+			}
+			if test.expectedMaxLen > 0 {
+				assert.LessOrEqual(t, len(errs[0].Error()), test.expectedMaxLen)
+			}
+		})
+	}
+}
+
+type FakeRandomNumberGenerator struct {
+	Number int
+}
+
+func (f FakeRandomNumberGenerator) GenerateInt63() int64 {
+	return int64(f.Number)
+}
+
+func (f FakeRandomNumberGenerator) Intn(n int) int {
+	return f.Number % n
+}
+
+func TestWeightedEndpoints(t *testing.T) {
+	extraInfo := `{"endpoints": [
+		{"url": "https://east.mocktioneer.test/openrtb2/auction", "weight": 1},
+		{"url": "https://west.mocktioneer.test/openrtb2/auction", "weight": 3}
+	]}`
+
+	tests := []struct {
+		name             string
+		randomNumber     int
+		expectedEndpoint string
+	}{
+		{
+			name:             "first-endpoint",
+			randomNumber:     0,
+			expectedEndpoint: "https://east.mocktioneer.test/openrtb2/auction",
+		},
+		{
+			name:             "second-endpoint-lower-bound",
+			randomNumber:     1,
+			expectedEndpoint: "https://west.mocktioneer.test/openrtb2/auction",
+		},
+		{
+			name:             "second-endpoint-upper-bound",
+			randomNumber:     3,
+			expectedEndpoint: "https://west.mocktioneer.test/openrtb2/auction",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, extraInfo).(*adapter)
+			bidder.randomGenerator = FakeRandomNumberGenerator{Number: test.randomNumber}
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id"}}}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, 1)
+			assert.Equal(t, test.expectedEndpoint, requests[0].Uri)
+		})
+	}
+}
+
+// countingRandomGenerator yields 0, 1, 2... so consecutive endpoint picks differ.
+type countingRandomGenerator struct {
+	calls int
+}
+
+func (g *countingRandomGenerator) GenerateInt63() int64 {
+	g.calls++
+	return int64(g.calls - 1)
+}
+
+func (g *countingRandomGenerator) Intn(n int) int {
+	g.calls++
+	return (g.calls - 1) % n
+}
+
+func TestWeightedEndpointsSplitDeals(t *testing.T) {
+	extraInfo := `{"splitDeals": true, "endpoints": [
+		{"url": "https://east.mocktioneer.test/openrtb2/auction", "weight": 1},
+		{"url": "https://west.mocktioneer.test/openrtb2/auction", "weight": 1}
+	]}`
+
+	bidder := buildTestBidder(t, extraInfo).(*adapter)
+	bidder.randomGenerator = &countingRandomGenerator{}
+
+	request := &openrtb2.BidRequest{
+		ID: "test-request-id",
+		Imp: []openrtb2.Imp{
+			{ID: "deal-imp", Banner: &openrtb2.Banner{}, PMP: &openrtb2.PMP{Deals: []openrtb2.Deal{{ID: "deal-1"}}}},
+			{ID: "open-imp", Banner: &openrtb2.Banner{}},
+		},
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+	assert.Empty(t, errs)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "https://east.mocktioneer.test/openrtb2/auction", requests[0].Uri)
+	assert.Equal(t, "https://east.mocktioneer.test/openrtb2/auction", requests[1].Uri)
+}
+
+func TestWeightedEndpointsSeed(t *testing.T) {
+	extraInfo := `{"endpointSeed": 42, "endpoints": [
+		{"url": "https://east.mocktioneer.test/openrtb2/auction", "weight": 1},
+		{"url": "https://west.mocktioneer.test/openrtb2/auction", "weight": 1}
+	]}`
+
+	pickEndpoints := func(bidder adapters.Bidder) []string {
+		var endpoints []string
+		for i := 0; i < 20; i++ {
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id"}}}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+			require.Empty(t, errs)
+			endpoints = append(endpoints, requests[0].Uri)
+		}
+		return endpoints
+	}
+
+	first := pickEndpoints(buildTestBidder(t, extraInfo))
+	second := pickEndpoints(buildTestBidder(t, extraInfo))
+
+	assert.Equal(t, first, second)
+	assert.Contains(t, first, "https://east.mocktioneer.test/openrtb2/auction")
+	assert.Contains(t, first, "https://west.mocktioneer.test/openrtb2/auction")
+}
+
+func TestWeightedEndpointsInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraInfo     string
+		expectedError string
+	}{
+		{
+			name:          "zero-weight",
+			extraInfo:     `{"endpoints": [{"url": "https://mocktioneer.test/openrtb2/auction", "weight": 0}]}`,
+			expectedError: "endpoint https://mocktioneer.test/openrtb2/auction must have a weight greater than 0",
+		},
+		{
+			name:          "malformed-template",
+			extraInfo:     `{"endpoints": [{"url": "{{Malformed}}", "weight": 1}]}`,
+			expectedError: `unable to parse endpoint url template: template: endpointTemplate:1: function "Malformed" not defined`,
+		},
+		{
+			name:          "http-with-require-https",
+			extraInfo:     `{"requireHTTPS": true, "endpoints": [{"url": "https://east.mocktioneer.test", "weight": 1}, {"url": "http://west.mocktioneer.test", "weight": 1}]}`,
+			expectedError: "endpoint must use https: http://west.mocktioneer.test",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+				Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+				ExtraAdapterInfo: test.extraInfo,
+			}, config.Server{})
+
+			assert.EqualError(t, buildErr, test.expectedError)
+		})
+	}
+}
+
+func TestEmptyResponseOnNoBid(t *testing.T) {
+	tests := []struct {
+		name             string
+		extraInfo        string
+		requestCur       []string
+		response         adapters.ResponseData
+		expectedResponse *adapters.BidderResponse
+	}{
+		{
+			name:             "status-204-disabled",
+			response:         adapters.ResponseData{StatusCode: http.StatusNoContent},
+			expectedResponse: nil,
+		},
+		{
+			name:             "status-204-request-currency",
+			extraInfo:        `{"emptyResponseOnNoBid": true}`,
+			requestCur:       []string{"EUR", "USD"},
+			response:         adapters.ResponseData{StatusCode: http.StatusNoContent},
+			expectedResponse: &adapters.BidderResponse{Currency: "EUR", Bids: []*adapters.TypedBid{}},
+		},
+		{
+			name:             "status-204-no-request-currency",
+			extraInfo:        `{"emptyResponseOnNoBid": true}`,
+			response:         adapters.ResponseData{StatusCode: http.StatusNoContent},
+			expectedResponse: &adapters.BidderResponse{Currency: "USD", Bids: []*adapters.TypedBid{}},
+		},
+		{
+			name:             "empty-seatbid-response-currency",
+			extraInfo:        `{"emptyResponseOnNoBid": true}`,
+			requestCur:       []string{"EUR"},
+			response:         adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(`{"id":"test-request-id","cur":"GBP","seatbid":[]}`)},
+			expectedResponse: &adapters.BidderResponse{Currency: "GBP", Bids: []*adapters.TypedBid{}},
+		},
+		{
+			name:             "empty-seatbid-disabled",
+			response:         adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(`{"id":"test-request-id","cur":"GBP","seatbid":[]}`)},
+			expectedResponse: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Cur: test.requestCur, Imp: []openrtb2.Imp{{ID: "test-imp-id"}}}
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, &test.response)
+
+			assert.Empty(t, errs)
+			assert.Equal(t, test.expectedResponse, bidderResponse)
+		})
+	}
+}
+
+func TestMaxBidsPerResponse(t *testing.T) {
+	request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body: []byte(`{"id":"test-request-id","seatbid":[
+			{"bid":[{"id":"bid-1","impid":"test-imp-id","price":1},{"id":"bid-2","impid":"test-imp-id","price":2}]},
+			{"bid":[{"id":"bid-3","impid":"test-imp-id","price":3},{"id":"bid-4","impid":"test-imp-id","price":4}]}
+		]}`),
+	}
+
+	tests := []struct {
+		name           string
+		extraInfo      string
+		expectedBidIDs []string
+		expectedErrors []string
+	}{
+		{
+			name:           "unlimited",
+			expectedBidIDs: []string{"bid-1", "bid-2", "bid-3", "bid-4"},
+		},
+		{
+			name:           "truncated",
+			extraInfo:      `{"maxBidsPerResponse": 3}`,
+			expectedBidIDs: []string{"bid-1", "bid-2", "bid-3"},
+			expectedErrors: []string{"1 bids dropped: the response exceeded the limit of 3 bids"},
+		},
+		{
+			name:           "at-limit",
+			extraInfo:      `{"maxBidsPerResponse": 4}`,
+			expectedBidIDs: []string{"bid-1", "bid-2", "bid-3", "bid-4"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			assert.Equal(t, test.expectedBidIDs, typedBidIDs(bidderResponse.Bids))
+		})
+	}
+}
+
+func TestMaxBidsPerResponseDedupeImps(t *testing.T) {
+	bidder := buildTestBidder(t, `{"dedupeImps": true, "maxBidsPerResponse": 1}`)
+
+	banner := &openrtb2.Banner{W: ptrutil.ToPtr[int64](300), H: ptrutil.ToPtr[int64](250)}
+	request := &openrtb2.BidRequest{
+		ID:  "test-request-id",
+		Imp: []openrtb2.Imp{{ID: "imp-1", Banner: banner}, {ID: "imp-2", Banner: banner}, {ID: "imp-3", Banner: banner}},
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+	require.Empty(t, errs)
+	require.Len(t, requests, 1)
+
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"bid-1","impid":"imp-1","price":1}]}]}`),
+	}
+	bidderResponse, errs := bidder.MakeBids(request, requests[0], response)
+
+	assertErrorMessages(t, []string{"2 bids dropped: the response exceeded the limit of 1 bids"}, errs)
+	require.NotNil(t, bidderResponse)
+	assert.Equal(t, []string{"bid-1"}, typedBidIDs(bidderResponse.Bids))
+}
+
+func TestMaxBidsPerResponseNegative(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"maxBidsPerResponse": -1}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, "maxBidsPerResponse must not be negative")
+}
+
+func typedBidIDs(bids []*adapters.TypedBid) []string {
+	ids := make([]string, 0, len(bids))
+	for _, bid := range bids {
+		ids = append(ids, bid.Bid.ID)
+	}
+	return ids
+}
+
+func TestGetHeaders(t *testing.T) {
+	tests := []struct {
+		name            string
+		request         *openrtb2.BidRequest
+		expectedHeaders map[string]string
+		absentHeaders   []string
+	}{
+		{
+			name:          "no-device",
+			request:       &openrtb2.BidRequest{},
+			absentHeaders: []string{"X-Limit-Ad-Tracking", "Dnt"},
+		},
+		{
+			name:          "lmt-and-dnt-unset",
+			request:       &openrtb2.BidRequest{Device: &openrtb2.Device{}},
+			absentHeaders: []string{"X-Limit-Ad-Tracking", "Dnt"},
+		},
+		{
+			name:            "lmt-and-dnt-off",
+			request:         &openrtb2.BidRequest{Device: &openrtb2.Device{Lmt: ptrutil.ToPtr[int8](0), DNT: ptrutil.ToPtr[int8](0)}},
+			expectedHeaders: map[string]string{"X-Limit-Ad-Tracking": "0", "Dnt": "0"},
+		},
+		{
+			name:            "lmt-and-dnt-on",
+			request:         &openrtb2.BidRequest{Device: &openrtb2.Device{Lmt: ptrutil.ToPtr[int8](1), DNT: ptrutil.ToPtr[int8](1)}},
+			expectedHeaders: map[string]string{"X-Limit-Ad-Tracking": "1", "Dnt": "1"},
+		},
+		{
+			name:          "click-browser-unset",
+			request:       &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1"}}},
+			absentHeaders: []string{"X-Click-Browser"},
+		},
+		{
+			name:            "click-browser-embedded",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", ClickBrowser: ptrutil.ToPtr[int8](0)}}},
+			expectedHeaders: map[string]string{"X-Click-Browser": "0"},
+		},
+		{
+			name:            "click-browser-native",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1"}, {ID: "imp-2", ClickBrowser: ptrutil.ToPtr[int8](1)}}},
+			expectedHeaders: map[string]string{"X-Click-Browser": "1"},
+		},
+		{
+			name:          "ad-position-unset",
+			request:       &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Banner: &openrtb2.Banner{}}}},
+			absentHeaders: []string{"X-Ad-Position"},
+		},
+		{
+			name:            "ad-position-above-the-fold",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Banner: &openrtb2.Banner{Pos: adcom1.PositionAboveFold.Ptr()}}}},
+			expectedHeaders: map[string]string{"X-Ad-Position": "1"},
+		},
+		{
+			name:            "ad-position-below-the-fold",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Video: &openrtb2.Video{}}, {ID: "imp-2", Banner: &openrtb2.Banner{Pos: adcom1.PositionBelowFold.Ptr()}}}},
+			expectedHeaders: map[string]string{"X-Ad-Position": "3"},
+		},
+		{
+			name:          "language-unset",
+			request:       &openrtb2.BidRequest{Device: &openrtb2.Device{}, Site: &openrtb2.Site{Content: &openrtb2.Content{}}},
+			absentHeaders: []string{"Accept-Language"},
+		},
+		{
+			name:            "device-language",
+			request:         &openrtb2.BidRequest{Device: &openrtb2.Device{Language: "fr"}},
+			expectedHeaders: map[string]string{"Accept-Language": "fr"},
+		},
+		{
+			name:            "device-language-over-content-language",
+			request:         &openrtb2.BidRequest{Device: &openrtb2.Device{Language: "fr"}, Site: &openrtb2.Site{Content: &openrtb2.Content{Language: "de"}}},
+			expectedHeaders: map[string]string{"Accept-Language": "fr"},
+		},
+		{
+			name:            "content-language",
+			request:         &openrtb2.BidRequest{Device: &openrtb2.Device{}, App: &openrtb2.App{Content: &openrtb2.Content{Language: "de"}}},
+			expectedHeaders: map[string]string{"Accept-Language": "de"},
+		},
+		{
+			name:          "auction-type-unset",
+			request:       &openrtb2.BidRequest{},
+			absentHeaders: []string{"X-Auction-Type"},
+		},
+		{
+			name:            "auction-type-first-price",
+			request:         &openrtb2.BidRequest{AT: 1},
+			expectedHeaders: map[string]string{"X-Auction-Type": "1"},
+		},
+		{
+			name:            "auction-type-second-price",
+			request:         &openrtb2.BidRequest{AT: 2},
+			expectedHeaders: map[string]string{"X-Auction-Type": "2"},
+		},
+		{
+			name:          "content-metadata-unset",
+			request:       &openrtb2.BidRequest{Site: &openrtb2.Site{Content: &openrtb2.Content{}}},
+			absentHeaders: []string{"X-Content-Genre", "X-Content-Cat"},
+		},
+		{
+			name:            "site-content-metadata",
+			request:         &openrtb2.BidRequest{Site: &openrtb2.Site{Content: &openrtb2.Content{Genre: "news", Cat: []string{"IAB12", "IAB12-1"}}}},
+			expectedHeaders: map[string]string{"X-Content-Genre": "news", "X-Content-Cat": "IAB12,IAB12-1"},
+		},
+		{
+			name:            "app-content-metadata",
+			request:         &openrtb2.BidRequest{App: &openrtb2.App{Content: &openrtb2.Content{Genre: "sports", Cat: []string{"IAB17"}}}},
+			expectedHeaders: map[string]string{"X-Content-Genre": "sports", "X-Content-Cat": "IAB17"},
+		},
+		{
+			name:          "coppa-off",
+			request:       &openrtb2.BidRequest{Regs: &openrtb2.Regs{COPPA: 0}},
+			absentHeaders: []string{"X-Coppa"},
+		},
+		{
+			name:            "coppa-on",
+			request:         &openrtb2.BidRequest{Regs: &openrtb2.Regs{COPPA: 1}},
+			expectedHeaders: map[string]string{"X-Coppa": "1"},
+		},
+		{
+			name:          "request-id-unset",
+			request:       &openrtb2.BidRequest{},
+			absentHeaders: []string{"X-Request-Id"},
+		},
+		{
+			name:            "request-id",
+			request:         &openrtb2.BidRequest{ID: "test-request-id"},
+			expectedHeaders: map[string]string{"X-Request-Id": "test-request-id"},
+		},
+		{
+			name:          "video-placement-unset",
+			request:       &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Video: &openrtb2.Video{}}}},
+			absentHeaders: []string{"X-Video-Placement"},
+		},
+		{
+			name:            "video-plcmt-instream",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Video: &openrtb2.Video{Plcmt: adcom1.VideoPlcmtInstream}}}},
+			expectedHeaders: map[string]string{"X-Video-Placement": "1"},
+		},
+		{
+			name:            "video-plcmt-outstream",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Video: &openrtb2.Video{Plcmt: adcom1.VideoPlcmtNoContent, Placement: adcom1.VideoPlacementInStream}}}},
+			expectedHeaders: map[string]string{"X-Video-Placement": "4"},
+		},
+		{
+			name:            "video-placement-instream",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Banner: &openrtb2.Banner{}}, {ID: "imp-2", Video: &openrtb2.Video{Placement: adcom1.VideoPlacementInStream}}}},
+			expectedHeaders: map[string]string{"X-Video-Placement": "1"},
+		},
+		{
+			name:            "video-placement-outstream",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Video: &openrtb2.Video{Placement: adcom1.VideoPlacementInArticle}}}},
+			expectedHeaders: map[string]string{"X-Video-Placement": "4"},
+		},
+		{
+			name:            "video-placement-interstitial",
+			request:         &openrtb2.BidRequest{Imp: []openrtb2.Imp{{ID: "imp-1", Video: &openrtb2.Video{Placement: adcom1.VideoPlacementAlwaysVisible}}}},
+			expectedHeaders: map[string]string{"X-Video-Placement": "3"},
+		},
+		{
+			name:          "final-decision-unset",
+			request:       &openrtb2.BidRequest{Source: &openrtb2.Source{}},
+			absentHeaders: []string{"X-Final-Decision"},
+		},
+		{
+			name:            "final-decision-0",
+			request:         &openrtb2.BidRequest{Source: &openrtb2.Source{FD: ptrutil.ToPtr[int8](0)}},
+			expectedHeaders: map[string]string{"X-Final-Decision": "0"},
+		},
+		{
+			name:            "final-decision-1",
+			request:         &openrtb2.BidRequest{Source: &openrtb2.Source{FD: ptrutil.ToPtr[int8](1)}},
+			expectedHeaders: map[string]string{"X-Final-Decision": "1"},
+		},
+		{
+			name:          "user-segments-outside-test-mode",
+			request:       &openrtb2.BidRequest{User: &openrtb2.User{Data: []openrtb2.Data{{Segment: []openrtb2.Segment{{ID: "seg-1"}}}}}},
+			absentHeaders: []string{"X-User-Segment-Count"},
+		},
+		{
+			name:          "user-data-unset",
+			request:       &openrtb2.BidRequest{Test: 1, User: &openrtb2.User{}},
+			absentHeaders: []string{"X-User-Segment-Count"},
+		},
+		{
+			name: "user-segments",
+			request: &openrtb2.BidRequest{Test: 1, User: &openrtb2.User{Data: []openrtb2.Data{
+				{ID: "provider-1", Segment: []openrtb2.Segment{{ID: "seg-1"}, {ID: "seg-2"}}},
+				{ID: "provider-2", Segment: []openrtb2.Segment{{ID: "seg-3"}}},
+				{ID: "provider-3"},
+			}}},
+			expectedHeaders: map[string]string{"X-User-Segment-Count": "3"},
+		},
+		{
+			name:          "debug-off",
+			request:       &openrtb2.BidRequest{Ext: json.RawMessage(`{"prebid":{"debug":false}}`)},
+			absentHeaders: []string{"X-Debug"},
+		},
+		{
+			name:            "debug-ext",
+			request:         &openrtb2.BidRequest{Ext: json.RawMessage(`{"prebid":{"debug":true}}`)},
+			expectedHeaders: map[string]string{"X-Debug": "1"},
+		},
+		{
+			name:            "debug-test",
+			request:         &openrtb2.BidRequest{Test: 1},
+			expectedHeaders: map[string]string{"X-Debug": "1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			headers := getHeaders(test.request)
+
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, headers.Get(name), name)
+			}
+			for _, name := range test.absentHeaders {
+				assert.NotContains(t, headers, name)
+			}
+		})
+	}
+}
+
+func TestMaxResponseDepth(t *testing.T) {
+	nested := func(depth int) string {
+		return `{"id":"test-request-id","ext":` + strings.Repeat("[", depth) + strings.Repeat("]", depth) + `}`
+	}
+
+	tests := []struct {
+		name           string
+		extraInfo      string
+		body           string
+		expectedErrors []string
+	}{
+		{
+			name: "default-limit-allows-normal-response",
+			body: `{"id":"test-request-id","seatbid":[{"bid":[{"id":"bid-1","impid":"test-imp-id","price":1,"ext":{"prebid":{"meta":{}}}}]}]}`,
+		},
+		{
+			name:           "default-limit-exceeded",
+			body:           nested(defaultMaxResponseDepth),
+			expectedErrors: []string{"response exceeds the maximum JSON depth of 128"},
+		},
+		{
+			name:      "configured-limit",
+			extraInfo: `{"maxResponseDepth": 4}`,
+			body:      nested(3),
+		},
+		{
+			name:           "configured-limit-exceeded",
+			extraInfo:      `{"maxResponseDepth": 4}`,
+			body:           nested(4),
+			expectedErrors: []string{"response exceeds the maximum JSON depth of 4"},
+		},
+		{
+			name:      "brackets-in-strings-ignored",
+			extraInfo: `{"maxResponseDepth": 5}`,
+			body:      `{"id":"test-request-id","seatbid":[{"bid":[{"id":"bid-1","impid":"test-imp-id","price":1,"adm":"[[[[[{{{{\"x\"}}"}]}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(test.body)}
+
+			_, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+		})
+	}
+}
+
+func TestExtraHeaders(t *testing.T) {
+	tests := []struct {
+		name            string
+		extraInfo       string
+		expectedHeaders map[string]string
+	}{
+		{
+			name:            "custom-header-added",
+			extraInfo:       `{"extraHeaders": {"x-api-key": "secret"}}`,
+			expectedHeaders: map[string]string{"X-Api-Key": "secret", "Accept": "application/json"},
+		},
+		{
+			name:            "existing-header-kept",
+			extraInfo:       `{"extraHeaders": {"accept": "text/plain", "User-Agent": "mock-agent"}}`,
+			expectedHeaders: map[string]string{"Accept": "application/json", "User-Agent": "test-ua"},
+		},
+		{
+			name:            "existing-header-overridden",
+			extraInfo:       `{"extraHeaders": {"accept": "text/plain", "User-Agent": "mock-agent"}, "overrideExisting": true}`,
+			expectedHeaders: map[string]string{"Accept": "text/plain", "User-Agent": "mock-agent"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID:     "test-request-id",
+				Imp:    []openrtb2.Imp{{ID: "test-imp-id"}},
+				Device: &openrtb2.Device{UA: "test-ua"},
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, 1)
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, []string{value}, requests[0].Headers.Values(name), name)
+			}
+		})
+	}
+}
+
+func TestExtraHeadersInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraInfo     string
+		expectedError string
+	}{
+		{
+			name:          "invalid-name",
+			extraInfo:     `{"extraHeaders": {"X Api Key": "secret"}}`,
+			expectedError: `invalid extra header name "X Api Key"`,
+		},
+		{
+			name:          "invalid-value",
+			extraInfo:     `{"extraHeaders": {"X-Api-Key": "secret\r\nX-Injected: 1"}}`,
+			expectedError: "invalid value for extra header X-Api-Key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+				Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+				ExtraAdapterInfo: test.extraInfo,
+			}, config.Server{})
+
+			assert.EqualError(t, buildErr, test.expectedError)
+		})
+	}
+}
+
+func TestImpWarningThreshold(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraInfo      string
+		impCount       int
+		expectedErrors []string
+	}{
+		{
+			name:     "disabled-by-default",
+			impCount: 100,
+		},
+		{
+			name:      "at-threshold",
+			extraInfo: `{"impWarningThreshold": 2}`,
+			impCount:  2,
+		},
+		{
+			name:           "above-threshold",
+			extraInfo:      `{"impWarningThreshold": 2}`,
+			impCount:       3,
+			expectedErrors: []string{"request has 3 imps, more than the warning threshold of 2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id"}
+			for i := 0; i < test.impCount; i++ {
+				request.Imp = append(request.Imp, openrtb2.Imp{ID: fmt.Sprintf("imp-%d", i)})
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			for _, err := range errs {
+				assert.IsType(t, &errortypes.Warning{}, err)
+			}
+			require.Len(t, requests, 1)
+			assert.Len(t, requests[0].ImpIDs, test.impCount)
+		})
+	}
+}
+
+func TestImpWarningThresholdNegative(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"impWarningThreshold": -1}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, "impWarningThreshold must not be negative")
+}
+
+func TestResolveAdmMacros(t *testing.T) {
+	body := `{"id":"test-auction-id","cur":"EUR","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","adid":"test-ad-id","price":2,` +
+		`"adm":"<img src='https://mocktioneer.test/win?p=${AUCTION_PRICE}&c=${AUCTION_CURRENCY}&x=${UNKNOWN}'>",` +
+		`"nurl":"https://mocktioneer.test/nurl?a=${AUCTION_ID}&i=${AUCTION_IMP_ID}&ad=${AUCTION_AD_ID}&p=${AUCTION_PRICE}"}]}]}`
+
+	tests := []struct {
+		name         string
+		extraInfo    string
+		requestExt   string
+		expectedAdM  string
+		expectedNURL string
+	}{
+		{
+			name:         "disabled",
+			expectedAdM:  "<img src='https://mocktioneer.test/win?p=${AUCTION_PRICE}&c=${AUCTION_CURRENCY}&x=${UNKNOWN}'>",
+			expectedNURL: "https://mocktioneer.test/nurl?a=${AUCTION_ID}&i=${AUCTION_IMP_ID}&ad=${AUCTION_AD_ID}&p=${AUCTION_PRICE}",
+		},
+		{
+			name:         "resolved",
+			extraInfo:    `{"resolveAdmMacros": true}`,
+			expectedAdM:  "<img src='https://mocktioneer.test/win?p=2&c=EUR&x=${UNKNOWN}'>",
+			expectedNURL: "https://mocktioneer.test/nurl?a=test-auction-id&i=test-imp-id&ad=test-ad-id&p=2",
+		},
+		{
+			name:         "resolved-after-currency-conversion",
+			extraInfo:    `{"resolveAdmMacros": true, "convertResponseCurrency": true}`,
+			requestExt:   `{"prebid":{"currency":{"rates":{"EUR":{"USD":1.25}}}}}`,
+			expectedAdM:  "<img src='https://mocktioneer.test/win?p=2.5&c=USD&x=${UNKNOWN}'>",
+			expectedNURL: "https://mocktioneer.test/nurl?a=test-auction-id&i=test-imp-id&ad=test-ad-id&p=2.5",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID:  "test-auction-id",
+				Cur: []string{"USD"},
+				Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}},
+			}
+			if test.requestExt != "" {
+				request.Ext = json.RawMessage(test.requestExt)
+			}
+			response := &adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(body)}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assert.Empty(t, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.Equal(t, test.expectedAdM, bidderResponse.Bids[0].Bid.AdM)
+			assert.Equal(t, test.expectedNURL, bidderResponse.Bids[0].Bid.NURL)
+		})
+	}
+}
+
+func TestDelayResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		test           int8
+		tmax           int64
+		impExts        []string
+		expectedDelay  time.Duration
+		expectedErrors []string
+	}{
+		{
+			name:    "ignored-outside-test-mode",
+			impExts: []string{`{"bidder":{"delayResponseMs":100}}`},
+		},
+		{
+			name:           "longest-imp-delay",
+			test:           1,
+			impExts:        []string{`{"bidder":{"delayResponseMs":100}}`, `{"bidder":{"delayResponseMs":250}}`, `{"bidder":{}}`},
+			expectedDelay:  250 * time.Millisecond,
+			expectedErrors: []string{"imps sent to mocktioneer without bidder params: imp-0, imp-1, imp-2"},
+		},
+		{
+			name:          "within-tmax",
+			test:          1,
+			tmax:          300,
+			impExts:       []string{`{"bidder":{"delayResponseMs":250,"bid":1}}`},
+			expectedDelay: 250 * time.Millisecond,
+		},
+		{
+			name:           "cut-to-tmax",
+			test:           1,
+			tmax:           100,
+			impExts:        []string{`{"bidder":{"delayResponseMs":250,"bid":1}}`},
+			expectedDelay:  100 * time.Millisecond,
+			expectedErrors: []string{"simulated delay of 250ms cut to the request's tmax of 100ms, the auction would have timed out"},
+		},
+		{
+			name:    "out-of-bounds",
+			test:    1,
+			impExts: []string{`{"bidder":{"delayResponseMs":-1}}`, `{"bidder":{"delayResponseMs":5001}}`},
+			expectedErrors: []string{
+				"imp imp-0: delayResponseMs must be between 0 and 5000",
+				"imp imp-1: delayResponseMs must be between 0 and 5000",
+				"imps sent to mocktioneer without bidder params: imp-0, imp-1",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "").(*adapter)
+			var delays []time.Duration
+			bidder.sleep = func(d time.Duration) { delays = append(delays, d) }
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Test: test.test, TMax: test.tmax}
+			for i, impExt := range test.impExts {
+				request.Imp = append(request.Imp, openrtb2.Imp{ID: fmt.Sprintf("imp-%d", i), Ext: json.RawMessage(impExt)})
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.Len(t, requests, 1)
+			if test.expectedDelay > 0 {
+				assert.Equal(t, []time.Duration{test.expectedDelay}, delays)
+			} else {
+				assert.Empty(t, delays)
+			}
+		})
+	}
+}
+
+type FakeUUIDGenerator struct {
+	ID  string
+	Err error
+}
+
+func (f FakeUUIDGenerator) Generate() (string, error) {
+	return f.ID, f.Err
+}
+
+func TestGeneratedBidID(t *testing.T) {
+	tests := []struct {
+		name           string
+		bid            string
+		uuidGenerator  FakeUUIDGenerator
+		expectedID     string
+		expectedBidExt string
+		expectedErrors []string
+	}{
+		{
+			name:           "generated-without-ids",
+			bid:            `{"impid":"test-imp-id","price":1,"ext":{"prebid":{"meta":{"adomain":["example.com"]}}}}`,
+			uuidGenerator:  FakeUUIDGenerator{ID: "generated-bid-id"},
+			expectedBidExt: `{"prebid":{"meta":{"adomain":["example.com"],"mediaType":"banner"},"bidid":"generated-bid-id"}}`,
+		},
+		{
+			name:           "bid-id-preserved",
+			bid:            `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			uuidGenerator:  FakeUUIDGenerator{ID: "generated-bid-id"},
+			expectedID:     "test-bid-id",
+			expectedBidExt: `{"prebid":{"meta":{"mediaType":"banner"}}}`,
+		},
+		{
+			name:           "prebid-bidid-preserved",
+			bid:            `{"impid":"test-imp-id","price":1,"ext":{"prebid":{"bidid":"upstream-bid-id"}}}`,
+			uuidGenerator:  FakeUUIDGenerator{ID: "generated-bid-id"},
+			expectedBidExt: `{"prebid":{"bidid":"upstream-bid-id","meta":{"mediaType":"banner"}}}`,
+		},
+		{
+			name:           "generator-failure",
+			bid:            `{"impid":"test-imp-id","price":1}`,
+			uuidGenerator:  FakeUUIDGenerator{Err: errors.New("no entropy")},
+			expectedBidExt: `{"prebid":{"meta":{"mediaType":"banner"}}}`,
+			expectedErrors: []string{"unable to generate a bid id for a bid on imp test-imp-id: no entropy"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "").(*adapter)
+			bidder.uuidGenerator = test.uuidGenerator
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[` + test.bid + `]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.Equal(t, test.expectedID, bidderResponse.Bids[0].Bid.ID)
+			assert.JSONEq(t, test.expectedBidExt, string(bidderResponse.Bids[0].Bid.Ext))
+		})
+	}
+}
+
+func TestGeneratedBidIDSeed(t *testing.T) {
+	generateBidIDs := func(bidder adapters.Bidder) []string {
+		request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+		response := &adapters.ResponseData{
+			StatusCode: http.StatusOK,
+			Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"impid":"test-imp-id","price":1},{"impid":"test-imp-id","price":2}]}]}`),
+		}
+
+		bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+		require.Empty(t, errs)
+
+		var bidIDs []string
+		for _, typedBid := range bidderResponse.Bids {
+			var bidExt openrtb_ext.ExtBid
+			require.NoError(t, json.Unmarshal(typedBid.Bid.Ext, &bidExt))
+			bidIDs = append(bidIDs, bidExt.Prebid.BidId)
+		}
+		return bidIDs
+	}
+
+	first := generateBidIDs(buildTestBidder(t, `{"bidIDSeed": 42}`))
+	second := generateBidIDs(buildTestBidder(t, `{"bidIDSeed": 42}`))
+
+	assert.Equal(t, first, second)
+	require.Len(t, first, 2)
+	assert.NotEqual(t, first[0], first[1])
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, first[0])
+}
+
+func TestStrictParams(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraInfo      string
+		expectedImpIDs []string
+		expectedErrors []string
+	}{
+		{
+			name:           "lenient-by-default",
+			expectedImpIDs: []string{"valid-imp", "invalid-imp"},
+		},
+		{
+			name:           "strict",
+			extraInfo:      `{"strictParams": true}`,
+			expectedImpIDs: []string{"valid-imp"},
+			expectedErrors: []string{"imp invalid-imp: invalid ext.bidder: cannot unmarshal openrtb_ext.ExtMocktioneer.Bid: invalid number"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID: "test-request-id",
+				Imp: []openrtb2.Imp{
+					{ID: "valid-imp", Ext: json.RawMessage(`{"bidder":{"bid":1.5}}`)},
+					{ID: "invalid-imp", Ext: json.RawMessage(`{"bidder":{"bid":"abc"}}`)},
+				},
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			for _, err := range errs {
+				assert.IsType(t, &errortypes.BadInput{}, err)
+			}
+			require.Len(t, requests, 1)
+			assert.Equal(t, test.expectedImpIDs, requests[0].ImpIDs)
+		})
+	}
+}
+
+func TestStrictParamsAllImpsInvalid(t *testing.T) {
+	bidder := buildTestBidder(t, `{"strictParams": true}`)
+
+	request := &openrtb2.BidRequest{
+		ID:  "test-request-id",
+		Imp: []openrtb2.Imp{{ID: "invalid-imp", Ext: json.RawMessage(`{"bidder":[]}`)}},
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+	assert.Empty(t, requests)
+	require.Len(t, errs, 1)
+	assert.IsType(t, &errortypes.BadInput{}, errs[0])
+}
+
+func TestAllImpsUnparseable(t *testing.T) {
+	bidder := buildTestBidder(t, "")
+
+	request := &openrtb2.BidRequest{
+		ID: "test-request-id",
+		Imp: []openrtb2.Imp{
+			{ID: "imp-1", Ext: json.RawMessage(`{"bidder":[]}`)},
+			{ID: "imp-2", Ext: json.RawMessage(`{"bidder":{"bid":"abc"}}`)},
+		},
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+	assert.Empty(t, requests)
+	assertErrorMessages(t, []string{"no valid imps remained: the mocktioneer params of every imp failed to parse (imp-1, imp-2)"}, errs)
+	assert.IsType(t, &errortypes.BadInput{}, errs[0])
+}
+
+func TestUnparseableImpWithDroppedImp(t *testing.T) {
+	bidder := buildTestBidder(t, "")
+
+	request := &openrtb2.BidRequest{
+		ID: "test-request-id",
+		Imp: []openrtb2.Imp{
+			{ID: "imp-1", Banner: &openrtb2.Banner{}, Ext: json.RawMessage(`{"bidder":[]}`)},
+			{ID: "imp-2", Banner: &openrtb2.Banner{}, Ext: json.RawMessage(`{"bidder":{"cur":"xx"}}`)},
+		},
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, []string{"imp-1"}, requests[0].ImpIDs)
+	assertErrorMessages(t, []string{"imp imp-2: cur xx is not a 3-letter ISO 4217 currency code"}, errs)
+}
+
+func TestOrderBidsByImp(t *testing.T) {
+	body := `{"id":"test-request-id","seatbid":[` +
+		`{"bid":[{"id":"unknown","impid":"imp-unknown","price":9},{"id":"imp-2-low","impid":"imp-2","price":1}]},` +
+		`{"bid":[{"id":"imp-1","impid":"imp-1","price":1},{"id":"imp-2-high","impid":"imp-2","price":3}]}]}`
+
+	tests := []struct {
+		name           string
+		extraInfo      string
+		expectedBidIDs []string
+	}{
+		{
+			name:           "response-order-by-default",
+			expectedBidIDs: []string{"unknown", "imp-2-low", "imp-1", "imp-2-high"},
+		},
+		{
+			name:           "imp-order",
+			extraInfo:      `{"orderBidsByImp": true}`,
+			expectedBidIDs: []string{"imp-1", "imp-2-high", "imp-2-low", "unknown"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID:  "test-request-id",
+				Imp: []openrtb2.Imp{{ID: "imp-1", Banner: &openrtb2.Banner{}}, {ID: "imp-2", Banner: &openrtb2.Banner{}}},
+			}
+			response := &adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(body)}
+
+			bidderResponse, _ := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			require.NotNil(t, bidderResponse)
+			assert.Equal(t, test.expectedBidIDs, typedBidIDs(bidderResponse.Bids))
+		})
+	}
+}
+
+func TestScenarioHeader(t *testing.T) {
+	tests := []struct {
+		name             string
+		requestExt       string
+		impExts          []string
+		expectedScenario string
+		expectedErrors   []string
+	}{
+		{
+			name:    "no-scenario",
+			impExts: []string{`{"bidder":{}}`},
+		},
+		{
+			name:             "request-level",
+			requestExt:       `{"prebid":{"bidderparams":{"scenario":"request-scenario"}}}`,
+			impExts:          []string{`{"bidder":{}}`},
+			expectedScenario: "request-scenario",
+		},
+		{
+			name:             "imp-level-wins-for-single-imp",
+			requestExt:       `{"prebid":{"bidderparams":{"scenario":"request-scenario"}}}`,
+			impExts:          []string{`{"bidder":{"scenario":"imp-scenario"}}`},
+			expectedScenario: "imp-scenario",
+		},
+		{
+			name:             "request-level-for-multi-imp",
+			requestExt:       `{"prebid":{"bidderparams":{"scenario":"request-scenario"}}}`,
+			impExts:          []string{`{"bidder":{"scenario":"imp-scenario"}}`, `{"bidder":{}}`},
+			expectedScenario: "request-scenario",
+		},
+		{
+			name:           "empty",
+			requestExt:     `{"prebid":{"bidderparams":{"scenario":""}}}`,
+			impExts:        []string{`{"bidder":{}}`},
+			expectedErrors: []string{`invalid scenario id "": it must be a non-empty token`},
+		},
+		{
+			name:           "not-a-token",
+			impExts:        []string{`{"bidder":{"scenario":"no bid\r\n"}}`},
+			expectedErrors: []string{`invalid scenario id "no bid\r\n": it must be a non-empty token`},
+		},
+		{
+			name:           "not-a-string",
+			requestExt:     `{"prebid":{"bidderparams":{"scenario":1}}}`,
+			impExts:        []string{`{"bidder":{}}`},
+			expectedErrors: []string{"invalid scenario id: Value is not a string: 1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id"}
+			if test.requestExt != "" {
+				request.Ext = json.RawMessage(test.requestExt)
+			}
+			for i, impExt := range test.impExts {
+				request.Imp = append(request.Imp, openrtb2.Imp{ID: fmt.Sprintf("imp-%d", i), Ext: json.RawMessage(impExt)})
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.Len(t, requests, 1)
+			assert.Equal(t, test.expectedScenario, requests[0].Headers.Get("X-Scenario-Id"))
+		})
+	}
+}
+
+func TestResponseBOMAndWhitespace(t *testing.T) {
+	response := `{"id":"test-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1}]}]}`
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "plain",
+			body: response,
+		},
+		{
+			name: "bom",
+			body: "\xef\xbb\xbf" + response,
+		},
+		{
+			name: "bom-and-whitespace",
+			body: "\xef\xbb\xbf \r\n" + response + "\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			responseData := &adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(test.body)}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, responseData)
+
+			assert.Empty(t, errs)
+			require.NotNil(t, bidderResponse)
+			assert.Equal(t, []string{"test-bid-id"}, typedBidIDs(bidderResponse.Bids))
+		})
+	}
+}
+
+func TestEmptyResponseBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedError bool
+	}{
+		{
+			name: "empty",
+			body: "",
+		},
+		{
+			name: "whitespace",
+			body: " \r\n\t",
+		},
+		{
+			name: "bom",
+			body: "\xef\xbb\xbf\n",
+		},
+		{
+			name:          "invalid",
+			body:          "{",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			responseData := &adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(test.body)}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, responseData)
+
+			assert.Nil(t, bidderResponse)
+			if !test.expectedError {
+				assert.Empty(t, errs)
+				return
+			}
+			require.Len(t, errs, 1)
+			assert.IsType(t, &errortypes.BadServerResponse{}, errs[0])
+		})
+	}
+}
+
+func TestLenientCurrency(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraInfo      string
+		expectedImpExt string
+		expectedErrors []string
+	}{
+		{
+			name:           "strict-by-default",
+			expectedErrors: []string{"imp test-imp-id: unable to convert bidfloor from EUR to USD: no currency rates"},
+		},
+		{
+			name:           "lenient",
+			extraInfo:      `{"lenientCurrency": true}`,
+			expectedImpExt: `{"bidder":{"bidFloorMultiplier":1.5,"bid":3}}`,
+			expectedErrors: []string{"imp test-imp-id: unable to convert bidfloor from EUR to USD: no currency rates, using the unconverted bidfloor"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID:  "test-request-id",
+				Cur: []string{"USD"},
+				Imp: []openrtb2.Imp{{
+					ID:          "test-imp-id",
+					BidFloor:    2,
+					BidFloorCur: "EUR",
+					Ext:         json.RawMessage(`{"bidder":{"bidFloorMultiplier":1.5}}`),
+				}},
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			if test.expectedImpExt == "" {
+				assert.Empty(t, requests)
+				assert.IsType(t, &errortypes.BadInput{}, errs[0])
+				return
+			}
+
+			assert.IsType(t, &errortypes.Warning{}, errs[0])
+			require.Len(t, requests, 1)
+			var body openrtb2.BidRequest
+			require.NoError(t, json.Unmarshal(requests[0].Body, &body))
+			assert.JSONEq(t, test.expectedImpExt, string(body.Imp[0].Ext))
+		})
+	}
+}
+
+func TestSplitDeals(t *testing.T) {
+	dealImp := func(id string) openrtb2.Imp {
+		return openrtb2.Imp{ID: id, Banner: &openrtb2.Banner{}, PMP: &openrtb2.PMP{Deals: []openrtb2.Deal{{ID: "deal-" + id}}}}
+	}
+	openImp := func(id string) openrtb2.Imp {
+		return openrtb2.Imp{ID: id, Banner: &openrtb2.Banner{}, PMP: &openrtb2.PMP{}}
+	}
+
+	tests := []struct {
+		name           string
+		extraInfo      string
+		imps           []openrtb2.Imp
+		expectedImpIDs [][]string
+	}{
+		{
+			name:           "single-request-by-default",
+			imps:           []openrtb2.Imp{dealImp("imp-1"), openImp("imp-2")},
+			expectedImpIDs: [][]string{{"imp-1", "imp-2"}},
+		},
+		{
+			name:           "deal-and-open-auction-requests",
+			extraInfo:      `{"splitDeals": true}`,
+			imps:           []openrtb2.Imp{openImp("imp-1"), dealImp("imp-2"), openImp("imp-3"), dealImp("imp-4")},
+			expectedImpIDs: [][]string{{"imp-2", "imp-4"}, {"imp-1", "imp-3"}},
+		},
+		{
+			name:           "deals-only",
+			extraInfo:      `{"splitDeals": true}`,
+			imps:           []openrtb2.Imp{dealImp("imp-1")},
+			expectedImpIDs: [][]string{{"imp-1"}},
+		},
+		{
+			name:           "open-auction-only",
+			extraInfo:      `{"splitDeals": true}`,
+			imps:           []openrtb2.Imp{openImp("imp-1")},
+			expectedImpIDs: [][]string{{"imp-1"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: test.imps}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, len(test.expectedImpIDs))
+			for i, requestData := range requests {
+				assert.Equal(t, test.expectedImpIDs[i], requestData.ImpIDs)
+
+				var body openrtb2.BidRequest
+				require.NoError(t, json.Unmarshal(requestData.Body, &body))
+				assert.Equal(t, test.expectedImpIDs[i], openrtb_ext.GetImpIDs(body.Imp))
+				for _, imp := range body.Imp {
+					for _, original := range test.imps {
+						if original.ID == imp.ID {
+							assert.Equal(t, original.PMP, imp.PMP)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExpectBidCountSplitRequests(t *testing.T) {
+	bidder := buildTestBidder(t, `{"splitDeals": true}`)
+
+	request := &openrtb2.BidRequest{
+		ID: "test-request-id",
+		Imp: []openrtb2.Imp{
+			{ID: "deal-imp", Banner: &openrtb2.Banner{}, Ext: json.RawMessage(`{"bidder":{"expectBidCount":1}}`)},
+			{ID: "open-imp", Banner: &openrtb2.Banner{}, Ext: json.RawMessage(`{"bidder":{"expectBidCount":1}}`)},
+		},
+	}
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"deal-imp","price":1,"dealid":"deal-1"}]}]}`),
+	}
+
+	_, errs := bidder.MakeBids(request, &adapters.RequestData{ImpIDs: []string{"deal-imp"}}, response)
+
+	assert.Empty(t, errs)
+}
+
+func TestMediaTypeEndpoints(t *testing.T) {
+	const mediaTypeEndpoints = `"mediaTypeEndpoints":{"banner":"https://banner.mocktioneer.test/auction","video":"https://video.mocktioneer.test/auction"}`
+
+	bannerImp := openrtb2.Imp{ID: "banner-imp", Banner: &openrtb2.Banner{}}
+	videoImp := openrtb2.Imp{ID: "video-imp", Video: &openrtb2.Video{}}
+	nativeImp := openrtb2.Imp{ID: "native-imp", Native: &openrtb2.Native{}}
+
+	type expectedRequest struct {
+		uri    string
+		impIDs []string
+	}
+
+	tests := []struct {
+		name             string
+		extraInfo        string
+		imps             []openrtb2.Imp
+		expectedRequests []expectedRequest
+	}{
+		{
+			name:      "mapped",
+			extraInfo: `{` + mediaTypeEndpoints + `}`,
+			imps:      []openrtb2.Imp{videoImp},
+			expectedRequests: []expectedRequest{
+				{uri: "https://video.mocktioneer.test/auction", impIDs: []string{"video-imp"}},
+			},
+		},
+		{
+			name:      "unmapped-goes-to-default-endpoint",
+			extraInfo: `{` + mediaTypeEndpoints + `}`,
+			imps:      []openrtb2.Imp{nativeImp},
+			expectedRequests: []expectedRequest{
+				{uri: "https://mocktioneer.test/openrtb2/auction", impIDs: []string{"native-imp"}},
+			},
+		},
+		{
+			name:      "unmapped-goes-to-fallback-endpoint",
+			extraInfo: `{` + mediaTypeEndpoints + `,"fallbackEndpoint":"https://fallback.mocktioneer.test/auction"}`,
+			imps:      []openrtb2.Imp{nativeImp},
+			expectedRequests: []expectedRequest{
+				{uri: "https://fallback.mocktioneer.test/auction", impIDs: []string{"native-imp"}},
+			},
+		},
+		{
+			name:      "split-by-endpoint",
+			extraInfo: `{` + mediaTypeEndpoints + `,"fallbackEndpoint":"https://fallback.mocktioneer.test/auction"}`,
+			imps:      []openrtb2.Imp{bannerImp, nativeImp, videoImp, {ID: "banner-imp-2", Banner: &openrtb2.Banner{}}},
+			expectedRequests: []expectedRequest{
+				{uri: "https://banner.mocktioneer.test/auction", impIDs: []string{"banner-imp", "banner-imp-2"}},
+				{uri: "https://fallback.mocktioneer.test/auction", impIDs: []string{"native-imp"}},
+				{uri: "https://video.mocktioneer.test/auction", impIDs: []string{"video-imp"}},
+			},
+		},
+		{
+			name:      "multi-format-imp-goes-to-fallback-endpoint",
+			extraInfo: `{` + mediaTypeEndpoints + `,"fallbackEndpoint":"https://fallback.mocktioneer.test/auction"}`,
+			imps:      []openrtb2.Imp{{ID: "multi-format-imp", Banner: &openrtb2.Banner{}, Video: &openrtb2.Video{}}},
+			expectedRequests: []expectedRequest{
+				{uri: "https://fallback.mocktioneer.test/auction", impIDs: []string{"multi-format-imp"}},
+			},
+		},
+		{
+			name:      "forced-media-type",
+			extraInfo: `{` + mediaTypeEndpoints + `}`,
+			imps: []openrtb2.Imp{
+				{ID: "forced-imp", Banner: &openrtb2.Banner{}, Ext: json.RawMessage(`{"bidder":{"mediaType":"video"}}`)},
+				{ID: "forced-imp-with-bid", Banner: &openrtb2.Banner{}, Ext: json.RawMessage(`{"bidder":{"mediaType":"video","bid":1}}`)},
+			},
+			expectedRequests: []expectedRequest{
+				{uri: "https://video.mocktioneer.test/auction", impIDs: []string{"forced-imp", "forced-imp-with-bid"}},
+			},
+		},
+		{
+			name: "single-request-by-default",
+			imps: []openrtb2.Imp{bannerImp, videoImp},
+			expectedRequests: []expectedRequest{
+				{uri: "https://mocktioneer.test/openrtb2/auction", impIDs: []string{"banner-imp", "video-imp"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: test.imps}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, len(test.expectedRequests))
+			for i, requestData := range requests {
+				assert.Equal(t, test.expectedRequests[i].uri, requestData.Uri)
+				assert.Equal(t, test.expectedRequests[i].impIDs, requestData.ImpIDs)
+			}
+		})
+	}
+}
+
+func TestInvalidMediaTypeEndpoints(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraInfo     string
+		expectedError string
+	}{
+		{
+			name:          "unknown-media-type",
+			extraInfo:     `{"mediaTypeEndpoints":{"display":"https://display.mocktioneer.test/auction"}}`,
+			expectedError: "mediaTypeEndpoints: invalid BidType: display",
+		},
+		{
+			name:          "invalid-template",
+			extraInfo:     `{"mediaTypeEndpoints":{"video":"{{.Host"}}`,
+			expectedError: "unable to parse video endpoint url template: template: endpointTemplate:1: unclosed action",
+		},
+		{
+			name:          "fallback-without-media-type-endpoints",
+			extraInfo:     `{"fallbackEndpoint":"https://fallback.mocktioneer.test/auction"}`,
+			expectedError: "fallbackEndpoint requires mediaTypeEndpoints",
+		},
+		{
+			name:          "media-type-endpoint-scheme-from-macro-with-require-https",
+			extraInfo:     `{"requireHTTPS":true,"mediaTypeEndpoints":{"video":"{{.Host}}/video"}}`,
+			expectedError: "endpoint must use https: /video",
+		},
+		{
+			name:          "http-fallback-with-require-https",
+			extraInfo:     `{"requireHTTPS":true,"mediaTypeEndpoints":{"video":"https://video.mocktioneer.test/auction"},"fallbackEndpoint":"http://fallback.mocktioneer.test/auction"}`,
+			expectedError: "endpoint must use https: http://fallback.mocktioneer.test/auction",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+				Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+				ExtraAdapterInfo: test.extraInfo,
+			}, config.Server{})
+
+			assert.EqualError(t, buildErr, test.expectedError)
+		})
+	}
+}
+
+func TestCompressRequest(t *testing.T) {
+	tests := []struct {
+		name               string
+		extraInfo          string
+		expectedCompressed bool
+	}{
+		{
+			name: "disabled-by-default",
+		},
+		{
+			name:      "below-default-threshold",
+			extraInfo: `{"compressRequest": true}`,
+		},
+		{
+			name:               "above-threshold",
+			extraInfo:          `{"compressRequest": true, "compressThreshold": 16}`,
+			expectedCompressed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, 1)
+
+			body := requests[0].Body
+			if test.expectedCompressed {
+				assert.Equal(t, "gzip", requests[0].Headers.Get("Content-Encoding"))
+				reader, err := gzip.NewReader(bytes.NewReader(body))
+				require.NoError(t, err)
+				body, err = io.ReadAll(reader)
+				require.NoError(t, err)
+			} else {
+				assert.Empty(t, requests[0].Headers.Get("Content-Encoding"))
+			}
+			assert.JSONEq(t, `{"id":"test-request-id","imp":[{"id":"test-imp-id","banner":{}}]}`, string(body))
+		})
+	}
+}
+
+func TestCompressThresholdNegative(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"compressRequest": true, "compressThreshold": -1}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, "compressThreshold must not be negative")
+}
+
+func TestMaxTargetingKeyLength(t *testing.T) {
+	bidder := buildTestBidder(t, `{"maxTargetingKeyLength": 8}`)
+
+	request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1,"ext":{"prebid":{"targeting":{"hb_mock":"a","hb_mock_scenario":"b"}}}}]}]}`),
+	}
+
+	bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+	assertErrorMessages(t, []string{"bid test-bid-id: targeting key hb_mock_scenario is longer than 8 characters, truncated to hb_mock_"}, errs)
+	require.NotNil(t, bidderResponse)
+	require.Len(t, bidderResponse.Bids, 1)
+	assert.JSONEq(t, `{"prebid":{"targeting":{"hb_mock":"a","hb_mock_":"b"},"meta":{"mediaType":"banner"}}}`, string(bidderResponse.Bids[0].Bid.Ext))
+}
+
+func TestMaxTargetingKeyLengthCollision(t *testing.T) {
+	bidder := buildTestBidder(t, "")
+
+	request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1,"ext":{"prebid":{"targeting":{"hb_cache_id_mocktioneer":"one","hb_cache_id_mocktioneer_x":"two"}}}}]}]}`),
+	}
+
+	bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+	assertErrorMessages(t, []string{
+		"bid test-bid-id: targeting key hb_cache_id_mocktioneer is longer than 20 characters, truncated to hb_cache_id_mocktion",
+		"bid test-bid-id: targeting key hb_cache_id_mocktioneer_x is longer than 20 characters and dropped, its truncated key hb_cache_id_mocktion is already set",
+	}, errs)
+	require.NotNil(t, bidderResponse)
+	require.Len(t, bidderResponse.Bids, 1)
+	assert.JSONEq(t, `{"prebid":{"targeting":{"hb_cache_id_mocktion":"one"},"meta":{"mediaType":"banner"}}}`, string(bidderResponse.Bids[0].Bid.Ext))
+}
+
+func TestMaxTargetingKeyLengthNegative(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"maxTargetingKeyLength": -1}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, "maxTargetingKeyLength must not be negative")
+}
+
+func TestCustomAdmTooLong(t *testing.T) {
+	bidder := buildTestBidder(t, "")
+
+	request := &openrtb2.BidRequest{
+		ID:  "test-request-id",
+		Imp: []openrtb2.Imp{{ID: "test-imp-id", Ext: json.RawMessage(`{"bidder":{"adm":` + quote(strings.Repeat("a", maxAdmLength+1)) + `}}`)}},
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+	assert.Empty(t, requests)
+	assertErrorMessages(t, []string{"imp test-imp-id: adm is longer than 65536 bytes"}, errs)
+	assert.IsType(t, &errortypes.BadInput{}, errs[0])
+}
+
+func TestSignBody(t *testing.T) {
+	// The well known HMAC-SHA256 of "The quick brown fox jumps over the lazy dog" with the key "key".
+	assert.Equal(t, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", signBody([]byte("The quick brown fox jumps over the lazy dog"), "key"))
+}
+
+func TestRequestHmacKey(t *testing.T) {
+	tests := []struct {
+		name              string
+		extraInfo         string
+		expectedSignature string
+	}{
+		{
+			name: "unsigned-by-default",
+		},
+		{
+			name:              "signed",
+			extraInfo:         `{"requestHmacKey": "test-key"}`,
+			expectedSignature: "75765eaa320a02035a901bc770c93608f3b522856a154136e285cfe13b071425",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, 1)
+			assert.Equal(t, `{"id":"test-request-id","imp":[{"id":"test-imp-id","banner":{}}]}`, string(requests[0].Body))
+			assert.Equal(t, test.expectedSignature, requests[0].Headers.Get("X-Request-Signature"))
+		})
+	}
+}
+
+func TestFollowRedirects(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraInfo     string
+		statusCode    int
+		expectedError string
+	}{
+		{
+			name:          "error-by-default",
+			statusCode:    http.StatusFound,
+			expectedError: "Unexpected status code: 302. Run with request.debug = 1 for more info",
+		},
+		{
+			name:          "location-reported",
+			extraInfo:     `{"followRedirects": true}`,
+			statusCode:    http.StatusFound,
+			expectedError: "mocktioneer redirected with status 302 to https://eu.mocktioneer.example/bid, which adapters can't follow; set the endpoint to it instead",
+		},
+		{
+			name:          "not-a-redirect",
+			extraInfo:     `{"followRedirects": true}`,
+			statusCode:    http.StatusBadGateway,
+			expectedError: "Unexpected status code: 502. Run with request.debug = 1 for more info",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id"}}}
+			response := &adapters.ResponseData{
+				StatusCode: test.statusCode,
+				Headers:    http.Header{"Location": []string{"https://eu.mocktioneer.example/bid"}},
+			}
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assert.Nil(t, bidderResponse)
+			assertErrorMessages(t, []string{test.expectedError}, errs)
+		})
+	}
+}
+
+func TestMetaMediaType(t *testing.T) {
+	tests := []struct {
+		name              string
+		bid               string
+		expectedBidType   openrtb_ext.BidType
+		expectedMediaType string
+	}{
+		{
+			name:              "banner",
+			bid:               `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":1}`,
+			expectedBidType:   openrtb_ext.BidTypeBanner,
+			expectedMediaType: "banner",
+		},
+		{
+			name:              "video",
+			bid:               `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":2}`,
+			expectedBidType:   openrtb_ext.BidTypeVideo,
+			expectedMediaType: "video",
+		},
+		{
+			name:              "audio",
+			bid:               `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":3}`,
+			expectedBidType:   openrtb_ext.BidTypeAudio,
+			expectedMediaType: "audio",
+		},
+		{
+			name:              "upstream-value-kept",
+			bid:               `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":2,"ext":{"prebid":{"meta":{"mediaType":"banner"}}}}`,
+			expectedBidType:   openrtb_ext.BidTypeVideo,
+			expectedMediaType: "banner",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}, Video: &openrtb2.Video{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[` + test.bid + `]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assert.Empty(t, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.Equal(t, test.expectedBidType, bidderResponse.Bids[0].BidType)
+
+			var bidExt openrtb_ext.ExtBid
+			require.NoError(t, json.Unmarshal(bidderResponse.Bids[0].Bid.Ext, &bidExt))
+			require.NotNil(t, bidExt.Prebid)
+			require.NotNil(t, bidExt.Prebid.Meta)
+			assert.Equal(t, test.expectedMediaType, bidExt.Prebid.Meta.MediaType)
+		})
+	}
+}
+
+func TestCpmBounds(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraInfo      string
+		test           int8
+		expectedPrices []float64
+		expectedErrors []string
+	}{
+		{
+			name:           "unbounded-by-default",
+			expectedPrices: []float64{0.1, 50, 3},
+		},
+		{
+			name:           "clamped",
+			extraInfo:      `{"minCpm": {"banner": 0.5}, "maxCpm": {"banner": 20, "video": 1}}`,
+			expectedPrices: []float64{0.5, 20, 1},
+		},
+		{
+			name:           "clamped-with-debug-notes",
+			extraInfo:      `{"minCpm": {"banner": 0.5}, "maxCpm": {"banner": 20, "video": 1}}`,
+			test:           1,
+			expectedPrices: []float64{0.5, 20, 1},
+			expectedErrors: []string{
+				"bid low-bid: price 0.1 raised to the banner minCpm of 0.5",
+				"bid high-bid: price 50 lowered to the banner maxCpm of 20",
+				"bid video-bid: price 3 lowered to the video maxCpm of 1",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID:   "test-request-id",
+				Imp:  []openrtb2.Imp{{ID: "banner-imp", Banner: &openrtb2.Banner{}}, {ID: "video-imp", Video: &openrtb2.Video{}}},
+				Test: test.test,
+			}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body: []byte(`{"id":"test-request-id","seatbid":[{"bid":[` +
+					`{"id":"low-bid","impid":"banner-imp","price":0.1},` +
+					`{"id":"high-bid","impid":"banner-imp","price":50},` +
+					`{"id":"video-bid","impid":"video-imp","price":3}]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			prices := make([]float64, 0, len(bidderResponse.Bids))
+			for _, typedBid := range bidderResponse.Bids {
+				prices = append(prices, typedBid.Bid.Price)
+			}
+			assert.Equal(t, test.expectedPrices, prices)
+		})
+	}
+}
+
+func TestCpmBoundsInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraInfo     string
+		expectedError string
+	}{
+		{
+			name:          "unknown-media-type",
+			extraInfo:     `{"minCpm": {"display": 1}}`,
+			expectedError: "minCpm: invalid BidType: display",
+		},
+		{
+			name:          "negative",
+			extraInfo:     `{"maxCpm": {"video": -1}}`,
+			expectedError: "maxCpm for video must not be negative",
+		},
+		{
+			name:          "min-above-max",
+			extraInfo:     `{"minCpm": {"banner": 5}, "maxCpm": {"banner": 1}}`,
+			expectedError: "minCpm for banner must not be above its maxCpm",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+				Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+				ExtraAdapterInfo: test.extraInfo,
+			}, config.Server{})
+
+			assert.EqualError(t, buildErr, test.expectedError)
+		})
+	}
+}
+
+func TestValidateEndpointDNS(t *testing.T) {
+	tests := []struct {
+		name          string
+		endpoint      string
+		extraInfo     string
+		expectedError string
+	}{
+		{
+			name:      "off-by-default",
+			endpoint:  "https://unresolvable.test/openrtb2/auction",
+			extraInfo: ``,
+		},
+		{
+			name:      "resolvable",
+			endpoint:  "https://mocktioneer.test/openrtb2/auction",
+			extraInfo: `{"validateEndpointDNS": true}`,
+		},
+		{
+			name:          "unresolvable",
+			endpoint:      "https://unresolvable.test/openrtb2/auction",
+			extraInfo:     `{"validateEndpointDNS": true}`,
+			expectedError: "unable to resolve endpoint host unresolvable.test: no such host",
+		},
+		{
+			name:          "unresolvable-weighted-endpoint",
+			endpoint:      "https://mocktioneer.test/openrtb2/auction",
+			extraInfo:     `{"validateEndpointDNS": true, "endpoints": [{"url": "https://mocktioneer.test/a", "weight": 1}, {"url": "https://unresolvable.test/b", "weight": 1}]}`,
+			expectedError: "unable to resolve endpoint host unresolvable.test: no such host",
+		},
+		{
+			name:          "unresolvable-media-type-endpoint",
+			endpoint:      "https://mocktioneer.test/openrtb2/auction",
+			extraInfo:     `{"validateEndpointDNS": true, "mediaTypeEndpoints": {"video": "https://unresolvable.test/video"}}`,
+			expectedError: "unable to resolve endpoint host unresolvable.test: no such host",
+		},
+		{
+			name:          "unresolvable-fallback-endpoint",
+			endpoint:      "https://mocktioneer.test/openrtb2/auction",
+			extraInfo:     `{"validateEndpointDNS": true, "mediaTypeEndpoints": {"video": "https://mocktioneer.test/video"}, "fallbackEndpoint": "https://unresolvable.test/fallback"}`,
+			expectedError: "unable to resolve endpoint host unresolvable.test: no such host",
+		},
+		{
+			name:      "macros-skipped",
+			endpoint:  "https://{{.Host}}/openrtb2/auction",
+			extraInfo: `{"validateEndpointDNS": true}`,
+		},
+		{
+			name:          "no-host",
+			endpoint:      "/openrtb2/auction",
+			extraInfo:     `{"validateEndpointDNS": true}`,
+			expectedError: "endpoint /openrtb2/auction has no host to resolve",
+		},
+	}
+
+	defer func(original func(string) ([]string, error)) { lookupHost = original }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		if host == "mocktioneer.test" {
+			return []string{"192.0.2.1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+				Endpoint:         test.endpoint,
+				ExtraAdapterInfo: test.extraInfo,
+			}, config.Server{})
+
+			if test.expectedError == "" {
+				assert.NoError(t, buildErr)
+			} else {
+				assert.EqualError(t, buildErr, test.expectedError)
+			}
+		})
+	}
+}
+
+func TestTopBidPerImpOnly(t *testing.T) {
+	body := `{"id":"test-request-id","seatbid":[` +
+		`{"bid":[{"id":"imp-1-low","impid":"imp-1","price":1},{"id":"imp-2-first","impid":"imp-2","price":2}]},` +
+		`{"bid":[{"id":"imp-1-high","impid":"imp-1","price":3},{"id":"imp-2-tie","impid":"imp-2","price":2}]}]}`
+
+	tests := []struct {
+		name           string
+		extraInfo      string
+		expectedBidIDs []string
+		expectedErrors []string
+	}{
+		{
+			name:           "all-bids-by-default",
+			expectedBidIDs: []string{"imp-1-low", "imp-2-first", "imp-1-high", "imp-2-tie"},
+		},
+		{
+			name:           "top-bids",
+			extraInfo:      `{"topBidPerImpOnly": true}`,
+			expectedBidIDs: []string{"imp-2-first", "imp-1-high"},
+			expectedErrors: []string{"2 bids dropped: only the top bid of each imp is kept"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID:  "test-request-id",
+				Imp: []openrtb2.Imp{{ID: "imp-1", Banner: &openrtb2.Banner{}}, {ID: "imp-2", Banner: &openrtb2.Banner{}}},
+			}
+			response := &adapters.ResponseData{StatusCode: http.StatusOK, Body: []byte(body)}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			assert.Equal(t, test.expectedBidIDs, typedBidIDs(bidderResponse.Bids))
+		})
+	}
+}
+
+func TestValidateSKAdN(t *testing.T) {
+	tests := []struct {
+		name          string
+		ext           string
+		expectedError string
+	}{
+		{
+			name: "no-skadn",
+			ext:  `{"prebid":{}}`,
+		},
+		{
+			name: "signed-fidelities",
+			ext:  `{"skadn":{"version":"4.0","network":"example.skadnetwork","campaign":45,"fidelities":[{"fidelity":0,"signature":"sig-0"},{"fidelity":1,"signature":"sig-1"}]}}`,
+		},
+		{
+			name:          "unsigned-fidelity",
+			ext:           `{"skadn":{"version":"4.0","network":"example.skadnetwork","campaign":45,"fidelities":[{"fidelity":0,"signature":"sig-0"},{"fidelity":1}]}}`,
+			expectedError: "bid test-bid-id has an incomplete skadn object, missing signature",
+		},
+		{
+			name:          "malformed",
+			ext:           `{"skadn":"example.skadnetwork"}`,
+			expectedError: "bid test-bid-id has a malformed skadn object",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateSKAdN(&openrtb2.Bid{ID: "test-bid-id", Ext: json.RawMessage(test.ext)})
+
+			if test.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedError)
+			}
+		})
+	}
+}
+
+func TestDedupeImps(t *testing.T) {
+	tests := []struct {
+		name              string
+		extraInfo         string
+		expectedImpIDs    []string
+		expectedReqImpIDs []string
+		expectedBids      []string
+	}{
+		{
+			name:              "all-imps-by-default",
+			expectedImpIDs:    []string{"imp-1", "imp-2", "imp-3"},
+			expectedReqImpIDs: []string{"imp-1", "imp-2", "imp-3"},
+			expectedBids:      []string{"bid-1:imp-1", "bid-2:imp-2"},
+		},
+		{
+			name:              "duplicates-collapsed",
+			extraInfo:         `{"dedupeImps": true}`,
+			expectedImpIDs:    []string{"imp-1", "imp-2"},
+			expectedReqImpIDs: []string{"imp-1", "imp-2", "imp-3"},
+			expectedBids:      []string{"bid-1:imp-1", "bid-2:imp-2", "bid-1-imp-3:imp-3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{
+				ID: "test-request-id",
+				Imp: []openrtb2.Imp{
+					{ID: "imp-1", Banner: &openrtb2.Banner{W: ptrutil.ToPtr[int64](300), H: ptrutil.ToPtr[int64](250)}},
+					{ID: "imp-2", Banner: &openrtb2.Banner{W: ptrutil.ToPtr[int64](728), H: ptrutil.ToPtr[int64](90)}},
+					{ID: "imp-3", Banner: &openrtb2.Banner{W: ptrutil.ToPtr[int64](300), H: ptrutil.ToPtr[int64](250)}},
+				},
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, 1)
+			var sent openrtb2.BidRequest
+			require.NoError(t, json.Unmarshal(requests[0].Body, &sent))
+			assert.Equal(t, test.expectedImpIDs, openrtb_ext.GetImpIDs(sent.Imp))
+			assert.Equal(t, test.expectedReqImpIDs, requests[0].ImpIDs)
+
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"bid-1","impid":"imp-1","price":1},{"id":"bid-2","impid":"imp-2","price":2}]}]}`),
+			}
+			bidderResponse, errs := bidder.MakeBids(request, requests[0], response)
+
+			assert.Empty(t, errs)
+			require.NotNil(t, bidderResponse)
+			bids := make([]string, 0, len(bidderResponse.Bids))
+			for _, typedBid := range bidderResponse.Bids {
+				bids = append(bids, typedBid.Bid.ID+":"+typedBid.Bid.ImpID)
+			}
+			assert.Equal(t, test.expectedBids, bids)
+		})
+	}
+}
+
+func TestDedupeImpsGeneratedBidID(t *testing.T) {
+	bidder := buildTestBidder(t, `{"dedupeImps": true, "bidIDSeed": 1}`)
+
+	banner := &openrtb2.Banner{W: ptrutil.ToPtr[int64](300), H: ptrutil.ToPtr[int64](250)}
+	request := &openrtb2.BidRequest{
+		ID:  "test-request-id",
+		Imp: []openrtb2.Imp{{ID: "imp-1", Banner: banner}, {ID: "imp-2", Banner: banner}},
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+	require.Empty(t, errs)
+	require.Len(t, requests, 1)
+
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"impid":"imp-1","price":1}]}]}`),
+	}
+	bidderResponse, errs := bidder.MakeBids(request, requests[0], response)
+
+	assert.Empty(t, errs)
+	require.NotNil(t, bidderResponse)
+	require.Len(t, bidderResponse.Bids, 2)
+
+	bidIDs := make([]string, 0, len(bidderResponse.Bids))
+	for _, typedBid := range bidderResponse.Bids {
+		assert.Empty(t, typedBid.Bid.ID)
+		var bidExt openrtb_ext.ExtBid
+		require.NoError(t, json.Unmarshal(typedBid.Bid.Ext, &bidExt))
+		require.NotNil(t, bidExt.Prebid)
+		require.NotEmpty(t, bidExt.Prebid.BidId)
+		bidIDs = append(bidIDs, bidExt.Prebid.BidId)
+	}
+	assert.NotEqual(t, bidIDs[0], bidIDs[1])
+}
+
+func TestDedupeImpsMissingImpID(t *testing.T) {
+	bidder := buildTestBidder(t, `{"dedupeImps": true}`)
+
+	video := &openrtb2.Video{W: ptrutil.ToPtr[int64](640), H: ptrutil.ToPtr[int64](480)}
+	request := &openrtb2.BidRequest{
+		ID:  "test-request-id",
+		Imp: []openrtb2.Imp{{ID: "imp-1", Video: video}, {ID: "imp-2", Video: video}},
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+	require.Empty(t, errs)
+	require.Len(t, requests, 1)
+
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"bid-1","price":1}]}]}`),
+	}
+	bidderResponse, errs := bidder.MakeBids(request, requests[0], response)
+
+	assert.Empty(t, errs)
+	require.NotNil(t, bidderResponse)
+	require.Len(t, bidderResponse.Bids, 2)
+	assert.Equal(t, "imp-1", bidderResponse.Bids[0].Bid.ImpID)
+	assert.Equal(t, openrtb_ext.BidTypeVideo, bidderResponse.Bids[0].BidType)
+	assert.Equal(t, "imp-2", bidderResponse.Bids[1].Bid.ImpID)
+}
+func TestParseImpExtLayouts(t *testing.T) {
+	tests := []struct {
+		name        string
+		ext         string
+		expectedBid float64
+	}{
+		{
+			name:        "bidder",
+			ext:         `{"bidder":{"bid":1.5}}`,
+			expectedBid: 1.5,
+		},
+		{
+			name:        "prebid-bidder",
+			ext:         `{"prebid":{"bidder":{"mocktioneer":{"bid":2.5}}}}`,
+			expectedBid: 2.5,
+		},
+		{
+			name:        "bidder-preferred",
+			ext:         `{"bidder":{"bid":1.5},"prebid":{"bidder":{"mocktioneer":{"bid":2.5}}}}`,
+			expectedBid: 1.5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			impExt, err := parseImpExt(&openrtb2.Imp{ID: "test-imp-id", Ext: json.RawMessage(test.ext)})
+
+			require.NoError(t, err)
+			require.NotNil(t, impExt.Bid)
+			assert.Equal(t, test.expectedBid, *impExt.Bid)
+		})
+	}
+}
+
+func TestTimeoutHeader(t *testing.T) {
+	tests := []struct {
+		name            string
+		extraInfo       string
+		tmax            int64
+		expectedHeaders map[string]string
+		absentHeaders   []string
+	}{
+		{
+			name:            "default-name",
+			tmax:            500,
+			expectedHeaders: map[string]string{"X-Timeout-Ms": "500"},
+		},
+		{
+			name:            "custom-name",
+			extraInfo:       `{"timeoutHeader": "X-Deadline"}`,
+			tmax:            500,
+			expectedHeaders: map[string]string{"X-Deadline": "500"},
+			absentHeaders:   []string{"X-Timeout-Ms"},
+		},
+		{
+			name:          "no-tmax",
+			absentHeaders: []string{"X-Timeout-Ms"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id"}}, TMax: test.tmax}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, 1)
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, requests[0].Headers.Get(name), name)
+			}
+			for _, name := range test.absentHeaders {
+				assert.NotContains(t, requests[0].Headers, name)
+			}
+		})
+	}
+}
+
+func TestTimeoutHeaderInvalid(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"timeoutHeader": "X Timeout"}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, `invalid timeout header name "X Timeout"`)
+}
+
+func TestTimeoutHeaderSetByAdapter(t *testing.T) {
+	tests := []string{"Content-Type", "content-encoding", "X-Request-Signature"}
+
+	for _, header := range tests {
+		t.Run(header, func(t *testing.T) {
+			_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+				Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+				ExtraAdapterInfo: `{"timeoutHeader": ` + quote(header) + `}`,
+			}, config.Server{})
+
+			assert.EqualError(t, buildErr, "timeout header "+header+" is set by the adapter itself")
+		})
+	}
+}
+
+func TestCOPPAStripsUserIDs(t *testing.T) {
+	tests := []struct {
+		name         string
+		coppa        int8
+		expectedUser string
+	}{
+		{
+			name:         "coppa-off",
+			expectedUser: `{"id":"test-user-id","buyeruid":"test-buyer-uid","eids":[{"source":"example.com","uids":[{"id":"test-eid"}]}]}`,
+		},
+		{
+			name:         "coppa-on",
+			coppa:        1,
+			expectedUser: `{"buyeruid":"test-buyer-uid"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{
+				ID:   "test-request-id",
+				Imp:  []openrtb2.Imp{{ID: "test-imp-id"}},
+				Regs: &openrtb2.Regs{COPPA: test.coppa},
+				User: &openrtb2.User{
+					ID:       "test-user-id",
+					BuyerUID: "test-buyer-uid",
+					EIDs:     []openrtb2.EID{{Source: "example.com", UIDs: []openrtb2.UID{{ID: "test-eid"}}}},
+				},
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, 1)
+			var sent struct {
+				User json.RawMessage `json:"user"`
+			}
+			require.NoError(t, json.Unmarshal(requests[0].Body, &sent))
+			assert.JSONEq(t, test.expectedUser, string(sent.User))
+			assert.Equal(t, "test-user-id", request.User.ID, "the incoming request must not be modified")
+		})
+	}
+}
+
+func TestMetaNetworkID(t *testing.T) {
+	tests := []struct {
+		name           string
+		bidExt         string
+		expectedBidExt string
+		expectedErrors []string
+	}{
+		{
+			name:           "no-network-id",
+			bidExt:         `{}`,
+			expectedBidExt: `{"prebid":{"meta":{"mediaType":"banner"}}}`,
+		},
+		{
+			name:           "converted-to-meta",
+			bidExt:         `{"networkId":123}`,
+			expectedBidExt: `{"networkId":123,"prebid":{"meta":{"mediaType":"banner","networkId":123}}}`,
+		},
+		{
+			name:           "string-converted-to-meta",
+			bidExt:         `{"networkId":"123"}`,
+			expectedBidExt: `{"networkId":"123","prebid":{"meta":{"mediaType":"banner","networkId":123}}}`,
+		},
+		{
+			name:           "existing-meta-kept",
+			bidExt:         `{"networkId":123,"prebid":{"meta":{"networkId":456}}}`,
+			expectedBidExt: `{"networkId":123,"prebid":{"meta":{"mediaType":"banner","networkId":456}}}`,
+		},
+		{
+			name:           "invalid",
+			bidExt:         `{"networkId":"network-1"}`,
+			expectedBidExt: `{"networkId":"network-1","prebid":{"meta":{"mediaType":"banner"}}}`,
+			expectedErrors: []string{"bid test-bid-id has an invalid networkId network-1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1,"ext":` + test.bidExt + `}]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.JSONEq(t, test.expectedBidExt, string(bidderResponse.Bids[0].Bid.Ext))
+		})
+	}
+}
+
+func TestDefaultCurrency(t *testing.T) {
+	tests := []struct {
+		name             string
+		extraInfo        string
+		requestCur       []string
+		responseCur      string
+		expectedCurrency string
+	}{
+		{
+			name:             "usd-by-default",
+			expectedCurrency: "USD",
+		},
+		{
+			name:             "configured",
+			extraInfo:        `{"defaultCurrency": "EUR"}`,
+			expectedCurrency: "EUR",
+		},
+		{
+			name:             "request-currency-wins",
+			extraInfo:        `{"defaultCurrency": "EUR"}`,
+			requestCur:       []string{"GBP"},
+			responseCur:      "GBP",
+			expectedCurrency: "GBP",
+		},
+		{
+			name:             "response-currency-wins",
+			extraInfo:        `{"defaultCurrency": "EUR"}`,
+			responseCur:      "JPY",
+			expectedCurrency: "JPY",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Cur: test.requestCur, Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","cur":` + quote(test.responseCur) + `,"seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1}]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assert.Empty(t, errs)
+			require.NotNil(t, bidderResponse)
+			assert.Equal(t, test.expectedCurrency, bidderResponse.Currency)
+		})
+	}
+}
+
+func TestDefaultCurrencyInvalid(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"defaultCurrency": "euro"}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, "defaultCurrency euro is not a 3-letter ISO 4217 currency code")
+}
+
+func TestBlockedAdomains(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraInfo      string
+		adomain        string
+		expectedBidIDs []string
+		expectedErrors []string
+	}{
+		{
+			name:           "no-blocklist",
+			adomain:        `["blocked.com"]`,
+			expectedBidIDs: []string{"test-bid-id"},
+		},
+		{
+			name:           "not-blocked",
+			extraInfo:      `{"blockedAdomains": ["blocked.com"]}`,
+			adomain:        `["advertiser.com","notblocked.com"]`,
+			expectedBidIDs: []string{"test-bid-id"},
+		},
+		{
+			name:           "blocked",
+			extraInfo:      `{"blockedAdomains": ["blocked.com"]}`,
+			adomain:        `["advertiser.com","blocked.com"]`,
+			expectedBidIDs: []string{},
+			expectedErrors: []string{"bid test-bid-id dropped: adomain blocked.com is blocked"},
+		},
+		{
+			name:           "case-insensitive",
+			extraInfo:      `{"blockedAdomains": ["Blocked.COM"]}`,
+			adomain:        `["BLOCKED.com."]`,
+			expectedBidIDs: []string{},
+			expectedErrors: []string{"bid test-bid-id dropped: adomain BLOCKED.com. is blocked"},
+		},
+		{
+			name:           "subdomain",
+			extraInfo:      `{"blockedAdomains": ["blocked.com"]}`,
+			adomain:        `["ads.blocked.com"]`,
+			expectedBidIDs: []string{},
+			expectedErrors: []string{"bid test-bid-id dropped: adomain ads.blocked.com is blocked"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1,"adomain":` + test.adomain + `}]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			assert.Equal(t, test.expectedBidIDs, typedBidIDs(bidderResponse.Bids))
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraInfo      string
+		expectedConfig string
+	}{
+		{
+			name:           "defaults",
+			expectedConfig: `{"endpoint":"https://mocktioneer.test/openrtb2/auction","maxResponseDepth":128,"minImps":1,"noBidStatusCodes":[204],"okStatusCodes":[200],"timeoutHeader":"X-Timeout-Ms","defaultCurrency":"USD","compressThreshold":1024,"maxTargetingKeyLength":20}`,
+		},
+		{
+			name:           "secrets-redacted",
+			extraInfo:      `{"requestHmacKey":"secret","extraHeaders":{"Authorization":"Bearer token"},"splitDeals":true}`,
+			expectedConfig: `{"endpoint":"https://mocktioneer.test/openrtb2/auction","maxResponseDepth":128,"minImps":1,"noBidStatusCodes":[204],"okStatusCodes":[200],"timeoutHeader":"X-Timeout-Ms","defaultCurrency":"USD","compressThreshold":1024,"maxTargetingKeyLength":20,"splitDeals":true,"requestHmacKey":"<REDACTED>","extraHeaders":{"Authorization":"<REDACTED>"}}`,
+		},
+		{
+			name:           "weighted-endpoints",
+			extraInfo:      `{"endpoints":[{"url":"https://a.mocktioneer.test","weight":1},{"url":"https://b.mocktioneer.test","weight":3}]}`,
+			expectedConfig: `{"endpoints":[{"url":"https://a.mocktioneer.test","weight":1},{"url":"https://b.mocktioneer.test","weight":3}],"maxResponseDepth":128,"minImps":1,"noBidStatusCodes":[204],"okStatusCodes":[200],"timeoutHeader":"X-Timeout-Ms","defaultCurrency":"USD","compressThreshold":1024,"maxTargetingKeyLength":20}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			config, err := json.Marshal(bidder.(*adapter).Config())
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expectedConfig, string(config))
+		})
+	}
+}
+
+func TestConfigDoesNotModifyAdapter(t *testing.T) {
+	bidder := buildTestBidder(t, `{"requestHmacKey":"secret","extraHeaders":{"Authorization":"Bearer token"}}`).(*adapter)
+
+	bidder.Config()
+
+	assert.Equal(t, "secret", bidder.extraInfo.RequestHmacKey)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer token"}, bidder.extraInfo.ExtraHeaders)
+}
+
+func TestSeatMediaTypes(t *testing.T) {
+	tests := []struct {
+		name         string
+		seat         string
+		seatExt      string
+		bid          string
+		expectedType openrtb_ext.BidType
+	}{
+		{
+			name:         "mapped-seat",
+			seat:         "video_dsp",
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			expectedType: openrtb_ext.BidTypeVideo,
+		},
+		{
+			name:         "unmapped-seat-falls-back-to-imp",
+			seat:         "other_dsp",
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			expectedType: openrtb_ext.BidTypeBanner,
+		},
+		{
+			name:         "mtype-wins",
+			seat:         "video_dsp",
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":4}`,
+			expectedType: openrtb_ext.BidTypeNative,
+		},
+		{
+			name:         "seat-ext-wins",
+			seat:         "video_dsp",
+			seatExt:      `,"ext":{"prebid":{"type":"native"}}`,
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			expectedType: openrtb_ext.BidTypeNative,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, `{"seatMediaTypes": {"video_dsp": "video"}}`)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"seat":` + quote(test.seat) + test.seatExt + `,"bid":[` + test.bid + `]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assert.Empty(t, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.Equal(t, test.expectedType, bidderResponse.Bids[0].BidType)
+		})
+	}
+}
+
+func TestSeatMediaTypesInvalid(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"seatMediaTypes": {"video_dsp": "ctv"}}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, "seatMediaTypes for seat video_dsp: invalid BidType: ctv")
+}
+
+func TestStatusCodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraInfo      string
+		statusCode     int
+		expectedBidIDs []string
+		expectedErrors []string
+	}{
+		{
+			name:           "ok-by-default",
+			statusCode:     http.StatusOK,
+			expectedBidIDs: []string{"test-bid-id"},
+		},
+		{
+			name:       "no-bid-by-default",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:           "accepted-errors-by-default",
+			statusCode:     http.StatusAccepted,
+			expectedErrors: []string{"Unexpected status code: 202. Run with request.debug = 1 for more info"},
+		},
+		{
+			name:       "configured-no-bid",
+			extraInfo:  `{"noBidStatusCodes": [202, 204]}`,
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:           "configured-ok",
+			extraInfo:      `{"okStatusCodes": [200, 203]}`,
+			statusCode:     http.StatusNonAuthoritativeInfo,
+			expectedBidIDs: []string{"test-bid-id"},
+		},
+		{
+			name:           "default-ok-not-configured",
+			extraInfo:      `{"okStatusCodes": [203]}`,
+			statusCode:     http.StatusOK,
+			expectedErrors: []string{"Unexpected status code: 200. Run with request.debug = 1 for more info"},
+		},
+		{
+			name:           "default-no-bid-not-configured",
+			extraInfo:      `{"noBidStatusCodes": [202]}`,
+			statusCode:     http.StatusNoContent,
+			expectedErrors: []string{"Unexpected status code: 204. Run with request.debug = 1 for more info"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: test.statusCode,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1}]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			if test.expectedBidIDs == nil {
+				assert.Nil(t, bidderResponse)
+				return
+			}
+			require.NotNil(t, bidderResponse)
+			assert.Equal(t, test.expectedBidIDs, typedBidIDs(bidderResponse.Bids))
+		})
+	}
+}
+
+func TestStatusCodesInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraInfo     string
+		expectedError string
+	}{
+		{
+			name:          "not-a-status",
+			extraInfo:     `{"okStatusCodes": [200, 2000]}`,
+			expectedError: "okStatusCodes: 2000 is not an HTTP status code",
+		},
+		{
+			name:          "in-both",
+			extraInfo:     `{"noBidStatusCodes": [200, 204]}`,
+			expectedError: "status code 200 can't be in both noBidStatusCodes and okStatusCodes",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+				Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+				ExtraAdapterInfo: test.extraInfo,
+			}, config.Server{})
+
+			assert.EqualError(t, buildErr, test.expectedError)
+		})
+	}
+}
+
+func TestSeedHeader(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestExt     string
+		impExt         string
+		expectedSeed   string
+		expectedErrors []string
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name:         "request-level",
+			requestExt:   `{"prebid":{"bidderparams":{"seed":42}}}`,
+			expectedSeed: "42",
+		},
+		{
+			name:         "imp-level-wins",
+			requestExt:   `{"prebid":{"bidderparams":{"seed":42}}}`,
+			impExt:       `{"bidder":{"seed":-7}}`,
+			expectedSeed: "-7",
+		},
+		{
+			name:           "not-an-integer",
+			requestExt:     `{"prebid":{"bidderparams":{"seed":1.5}}}`,
+			expectedErrors: []string{"request seed must be an integer"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{
+				ID:  "test-request-id",
+				Imp: []openrtb2.Imp{{ID: "test-imp-id", Ext: json.RawMessage(test.impExt)}},
+				Ext: json.RawMessage(test.requestExt),
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.Len(t, requests, 1)
+			if test.expectedSeed == "" {
+				assert.NotContains(t, requests[0].Headers, "X-Seed")
+				return
+			}
+			assert.Equal(t, test.expectedSeed, requests[0].Headers.Get("X-Seed"))
+		})
+	}
+}
+
+func TestDealPriority(t *testing.T) {
+	bidder := buildTestBidder(t, "")
+
+	request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body: []byte(`{"id":"test-request-id","seatbid":[{"bid":[` +
+			`{"id":"bid-deal-low","impid":"test-imp-id","price":3,"dealid":"deal-1","ext":{"prebid":{"dealpriority":1}}},` +
+			`{"id":"bid-deal-high","impid":"test-imp-id","price":1,"dealid":"deal-2","ext":{"prebid":{"dealpriority":5}}},` +
+			`{"id":"bid-open","impid":"test-imp-id","price":2}` +
+			`]}]}`),
+	}
+
+	bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+	assert.Empty(t, errs)
+	require.NotNil(t, bidderResponse)
+	priorities := make(map[string]int, len(bidderResponse.Bids))
+	for _, typedBid := range bidderResponse.Bids {
+		priorities[typedBid.Bid.ID] = typedBid.DealPriority
+	}
+	assert.Equal(t, map[string]int{"bid-deal-low": 1, "bid-deal-high": 5, "bid-open": 0}, priorities)
+}
+
+func TestFillBannerSize(t *testing.T) {
+	tests := []struct {
+		name           string
+		imp            openrtb2.Imp
+		bid            string
+		expectedW      int64
+		expectedH      int64
+		expectedErrors []string
+	}{
+		{
+			name:      "banner-size",
+			imp:       openrtb2.Imp{ID: "test-imp-id", Banner: &openrtb2.Banner{W: ptrutil.ToPtr[int64](728), H: ptrutil.ToPtr[int64](90)}},
+			bid:       `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			expectedW: 728,
+			expectedH: 90,
+		},
+		{
+			name:      "sole-format",
+			imp:       openrtb2.Imp{ID: "test-imp-id", Banner: &openrtb2.Banner{Format: []openrtb2.Format{{W: 300, H: 250}}}},
+			bid:       `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			expectedW: 300,
+			expectedH: 250,
+		},
+		{
+			name:           "several-formats",
+			imp:            openrtb2.Imp{ID: "test-imp-id", Banner: &openrtb2.Banner{Format: []openrtb2.Format{{W: 300, H: 250}, {W: 300, H: 600}}}},
+			bid:            `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			expectedErrors: []string{"bid test-bid-id has no size and imp test-imp-id has several formats, leaving it unsized"},
+		},
+		{
+			name:      "bid-size-kept",
+			imp:       openrtb2.Imp{ID: "test-imp-id", Banner: &openrtb2.Banner{Format: []openrtb2.Format{{W: 300, H: 250}, {W: 300, H: 600}}}},
+			bid:       `{"id":"test-bid-id","impid":"test-imp-id","price":1,"w":300,"h":600}`,
+			expectedW: 300,
+			expectedH: 600,
+		},
+		{
+			name: "video-untouched",
+			imp:  openrtb2.Imp{ID: "test-imp-id", Banner: &openrtb2.Banner{Format: []openrtb2.Format{{W: 300, H: 250}}}, Video: &openrtb2.Video{}},
+			bid:  `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":2}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{test.imp}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[` + test.bid + `]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.Equal(t, test.expectedW, bidderResponse.Bids[0].Bid.W)
+			assert.Equal(t, test.expectedH, bidderResponse.Bids[0].Bid.H)
+		})
+	}
+}
+
+func TestExpectEndpoint(t *testing.T) {
+	tests := []struct {
+		name           string
+		impExt         string
+		expectedErrors []string
+	}{
+		{
+			name:   "matching",
+			impExt: `{"bidder":{"expectEndpoint":"https://mocktioneer.test/openrtb2/auction"}}`,
+		},
+		{
+			name:           "mismatching",
+			impExt:         `{"bidder":{"expectEndpoint":"https://eu.mocktioneer.test/openrtb2/auction"}}`,
+			expectedErrors: []string{"imp test-imp-id was sent to https://mocktioneer.test/openrtb2/auction instead of the expected endpoint https://eu.mocktioneer.test/openrtb2/auction"},
+		},
+		{
+			name:           "invalid",
+			impExt:         `{"bidder":{"expectEndpoint":"/openrtb2/auction"}}`,
+			expectedErrors: []string{"imp test-imp-id: expectEndpoint must be an absolute url", "imp test-imp-id was sent to https://mocktioneer.test/openrtb2/auction instead of the expected endpoint /openrtb2/auction"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Ext: json.RawMessage(test.impExt)}}}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.Len(t, requests, 1)
+			assert.Equal(t, "https://mocktioneer.test/openrtb2/auction", requests[0].Uri)
+		})
+	}
+}
+
+func TestEchoedParams(t *testing.T) {
+	tests := []struct {
+		name           string
+		bidExt         string
+		expectedBidExt string
+	}{
+		{
+			name:           "moved-to-bidder",
+			bidExt:         `{"mocktioneer":{"echo":{"bid":1.5,"scenario":"no-bid"}}}`,
+			expectedBidExt: `{"prebid":{"bidder":{"mocktioneer":{"bid":1.5,"scenario":"no-bid"}},"meta":{"mediaType":"banner"}}}`,
+		},
+		{
+			name:           "other-fields-kept",
+			bidExt:         `{"mocktioneer":{"echo":{"bid":1.5},"trace":"abc"}}`,
+			expectedBidExt: `{"mocktioneer":{"trace":"abc"},"prebid":{"bidder":{"mocktioneer":{"bid":1.5}},"meta":{"mediaType":"banner"}}}`,
+		},
+		{
+			name:           "no-echo",
+			bidExt:         `{"mocktioneer":{"trace":"abc"}}`,
+			expectedBidExt: `{"mocktioneer":{"trace":"abc"},"prebid":{"meta":{"mediaType":"banner"}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1,"ext":` + test.bidExt + `}]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assert.Empty(t, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.JSONEq(t, test.expectedBidExt, string(bidderResponse.Bids[0].Bid.Ext))
+		})
+	}
+}
+
+func TestZeroBidParam(t *testing.T) {
+	tests := []struct {
+		name           string
+		impExt         string
+		expectedImpExt string
+	}{
+		{
+			name:   "absent",
+			impExt: `{"bidder":{}}`,
+		},
+		{
+			name:           "explicit-zero",
+			impExt:         `{"bidder":{"bid":0}}`,
+			expectedImpExt: `{"bidder":{"bid":0}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Ext: json.RawMessage(test.impExt)}}}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			require.Len(t, requests, 1)
+			var body openrtb2.BidRequest
+			require.NoError(t, json.Unmarshal(requests[0].Body, &body))
+			if test.expectedImpExt == "" {
+				assert.Empty(t, body.Imp[0].Ext)
+				return
+			}
+			assert.JSONEq(t, test.expectedImpExt, string(body.Imp[0].Ext))
+		})
+	}
+}
+
+func TestCompanionBids(t *testing.T) {
+	tests := []struct {
+		name              string
+		imp               openrtb2.Imp
+		bid               string
+		expectedType      openrtb_ext.BidType
+		expectedCompanion bool
+	}{
+		{
+			name:              "companion",
+			imp:               openrtb2.Imp{ID: "test-imp-id", Video: &openrtb2.Video{CompanionAd: []openrtb2.Banner{{W: ptrutil.ToPtr[int64](300), H: ptrutil.ToPtr[int64](250)}}}},
+			bid:               `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":1}`,
+			expectedType:      openrtb_ext.BidTypeBanner,
+			expectedCompanion: true,
+		},
+		{
+			name:         "video-bid-for-companion-imp",
+			imp:          openrtb2.Imp{ID: "test-imp-id", Video: &openrtb2.Video{CompanionAd: []openrtb2.Banner{{W: ptrutil.ToPtr[int64](300), H: ptrutil.ToPtr[int64](250)}}}},
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":2}`,
+			expectedType: openrtb_ext.BidTypeVideo,
+		},
+		{
+			name:         "video-imp-without-companions",
+			imp:          openrtb2.Imp{ID: "test-imp-id", Video: &openrtb2.Video{}},
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":1}`,
+			expectedType: openrtb_ext.BidTypeBanner,
+		},
+		{
+			name:         "multiformat-imp",
+			imp:          openrtb2.Imp{ID: "test-imp-id", Banner: &openrtb2.Banner{}, Video: &openrtb2.Video{CompanionAd: []openrtb2.Banner{{}}}},
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1,"mtype":1}`,
+			expectedType: openrtb_ext.BidTypeBanner,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, "")
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{test.imp}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[` + test.bid + `]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assert.Empty(t, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.Equal(t, test.expectedType, bidderResponse.Bids[0].BidType)
+			var bidExt openrtb_ext.ExtBid
+			require.NoError(t, json.Unmarshal(bidderResponse.Bids[0].Bid.Ext, &bidExt))
+			require.NotNil(t, bidExt.Prebid)
+			require.NotNil(t, bidExt.Prebid.Meta)
+			assert.Equal(t, string(test.expectedType), bidExt.Prebid.Meta.MediaType)
+
+			var mocktioneerExt struct {
+				Mocktioneer struct {
+					Companion bool `json:"companion"`
+				} `json:"mocktioneer"`
+			}
+			require.NoError(t, json.Unmarshal(bidderResponse.Bids[0].Bid.Ext, &mocktioneerExt))
+			assert.Equal(t, test.expectedCompanion, mocktioneerExt.Mocktioneer.Companion)
+		})
+	}
+}
+
+func TestUserDataForwarded(t *testing.T) {
+	bidder := buildTestBidder(t, "")
+
+	userData := []openrtb2.Data{
+		{ID: "provider-2", Name: "second", Segment: []openrtb2.Segment{{ID: "seg-b"}, {ID: "seg-a", Value: "2"}}},
+		{ID: "provider-1", Name: "first", Segment: []openrtb2.Segment{{ID: "seg-c"}}},
+	}
+	request := &openrtb2.BidRequest{
+		ID:   "test-request-id",
+		Imp:  []openrtb2.Imp{{ID: "test-imp-id", Ext: json.RawMessage(`{"bidder":{"bid":1}}`)}},
+		User: &openrtb2.User{ID: "user-id", Data: userData},
+		Test: 1,
+	}
+	requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+	assert.Empty(t, errs)
+	require.Len(t, requests, 1)
+	var body openrtb2.BidRequest
+	require.NoError(t, json.Unmarshal(requests[0].Body, &body))
+	require.NotNil(t, body.User)
+	assert.Equal(t, userData, body.User.Data)
+	assert.Equal(t, "3", requests[0].Headers.Get("X-User-Segment-Count"))
+}
+
+func TestCridPrefix(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraInfo      string
+		bid            string
+		uuidGenerator  FakeUUIDGenerator
+		expectedCrID   string
+		expectedErrors []string
+	}{
+		{
+			name:         "no-prefix",
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1,"crid":"crid-1"}`,
+			expectedCrID: "crid-1",
+		},
+		{
+			name:         "no-prefix-no-crid",
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			expectedCrID: "",
+		},
+		{
+			name:         "prefixed",
+			extraInfo:    `{"cridPrefix": "mock-"}`,
+			bid:          `{"id":"test-bid-id","impid":"test-imp-id","price":1,"crid":"crid-1"}`,
+			expectedCrID: "mock-crid-1",
+		},
+		{
+			name:          "generated",
+			extraInfo:     `{"cridPrefix": "mock-"}`,
+			bid:           `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			uuidGenerator: FakeUUIDGenerator{ID: "generated-crid"},
+			expectedCrID:  "mock-generated-crid",
+		},
+		{
+			name:           "generator-failure",
+			extraInfo:      `{"cridPrefix": "mock-"}`,
+			bid:            `{"id":"test-bid-id","impid":"test-imp-id","price":1}`,
+			uuidGenerator:  FakeUUIDGenerator{Err: errors.New("no entropy")},
+			expectedErrors: []string{"unable to generate a crid for bid test-bid-id: no entropy"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo).(*adapter)
+			bidder.uuidGenerator = test.uuidGenerator
+
+			request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+			response := &adapters.ResponseData{
+				StatusCode: http.StatusOK,
+				Body:       []byte(`{"id":"test-request-id","seatbid":[{"bid":[` + test.bid + `]}]}`),
+			}
+
+			bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+			assertErrorMessages(t, test.expectedErrors, errs)
+			require.NotNil(t, bidderResponse)
+			require.Len(t, bidderResponse.Bids, 1)
+			assert.Equal(t, test.expectedCrID, bidderResponse.Bids[0].Bid.CrID)
+		})
+	}
+}
+
+func TestResponseIDMismatch(t *testing.T) {
+	bidder := buildTestBidder(t, "")
+
+	request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{{ID: "test-imp-id", Banner: &openrtb2.Banner{}}}}
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"id":"other-request-id","seatbid":[{"bid":[{"id":"test-bid-id","impid":"test-imp-id","price":1}]}]}`),
+	}
+
+	bidderResponse, errs := bidder.MakeBids(request, &adapters.RequestData{}, response)
+
+	assertErrorMessages(t, []string{`response id "other-request-id" doesn't match the request id "test-request-id"`}, errs)
+	assert.IsType(t, &errortypes.Warning{}, errs[0])
+	require.NotNil(t, bidderResponse)
+	assert.Equal(t, []string{"test-bid-id"}, typedBidIDs(bidderResponse.Bids))
+}
+
+func TestMinImps(t *testing.T) {
+	tests := []struct {
+		name             string
+		extraInfo        string
+		impCount         int
+		expectedRequests int
+	}{
+		{
+			name:             "sent-by-default",
+			impCount:         1,
+			expectedRequests: 1,
+		},
+		{
+			name:      "too-few-imps",
+			extraInfo: `{"minImps": 3}`,
+			impCount:  2,
+		},
+		{
+			name:             "enough-imps",
+			extraInfo:        `{"minImps": 3}`,
+			impCount:         3,
+			expectedRequests: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bidder := buildTestBidder(t, test.extraInfo)
+
+			request := &openrtb2.BidRequest{ID: "test-request-id"}
+			for i := 0; i < test.impCount; i++ {
+				request.Imp = append(request.Imp, openrtb2.Imp{ID: fmt.Sprintf("imp-%d", i), Banner: &openrtb2.Banner{}})
+			}
+			requests, errs := bidder.MakeRequests(request, &adapters.ExtraRequestInfo{})
+
+			assert.Empty(t, errs)
+			assert.Len(t, requests, test.expectedRequests)
+		})
+	}
+}
+
+func TestMinImpsNegative(t *testing.T) {
+	_, buildErr := Builder(openrtb_ext.BidderMocktioneer, config.Adapter{
+		Endpoint:         "https://mocktioneer.test/openrtb2/auction",
+		ExtraAdapterInfo: `{"minImps": -1}`,
+	}, config.Server{})
+
+	assert.EqualError(t, buildErr, "minImps must not be negative")
+}
+
+func TestBidTagID(t *testing.T) {
+	request := &openrtb2.BidRequest{ID: "test-request-id", Imp: []openrtb2.Imp{
+		{ID: "imp-1", TagID: "tag-1", Banner: &openrtb2.Banner{}},
+		{ID: "imp-2", TagID: "tag-2", Banner: &openrtb2.Banner{}},
+		{ID: "imp-3", Banner: &openrtb2.Banner{}},
+	}}
+	response := &adapters.ResponseData{
+		StatusCode: http.StatusOK,
+		Body: []byte(`{"id":"test-request-id","seatbid":[{"bid":[` +
+			`{"id":"bid-1","impid":"imp-1","price":1,"ext":{"networkName":"mock"}},` +
+			`{"id":"bid-2","impid":"imp-2","price":1,"ext":{"tagid":"upstream-tag"}},` +
+			`{"id":"bid-3","impid":"imp-3","price":1}` +
+			`]}]}`),
+	}
+
+	bidderResponse, errs := buildTestBidder(t, "").MakeBids(request, &adapters.RequestData{}, response)
+
+	assert.Empty(t, errs)
+	require.NotNil(t, bidderResponse)
+	require.Len(t, bidderResponse.Bids, 3)
+	assert.JSONEq(t, `{"networkName":"mock","tagid":"tag-1","prebid":{"meta":{"mediaType":"banner"}}}`, string(bidderResponse.Bids[0].Bid.Ext))
+	assert.JSONEq(t, `{"tagid":"upstream-tag","prebid":{"meta":{"mediaType":"banner"}}}`, string(bidderResponse.Bids[1].Bid.Ext))
+	assert.JSONEq(t, `{"prebid":{"meta":{"mediaType":"banner"}}}`, string(bidderResponse.Bids[2].Bid.Ext))
+}
@@ -0,0 +1,105 @@
+package mocktioneer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/prebid/prebid-server/v3/openrtb_ext"
+)
+
+func TestValidParams(t *testing.T) {
+	validator, err := openrtb_ext.NewBidderParamsValidator("../../static/bidder-params")
+	if err != nil {
+		t.Fatalf("Failed to fetch the json-schemas. %v", err)
+	}
+
+	for _, validParam := range validParams {
+		if err := validator.Validate(openrtb_ext.BidderMocktioneer, json.RawMessage(validParam)); err != nil {
+			t.Errorf("Schema rejected mocktioneer params: %s", validParam)
+		}
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	validator, err := openrtb_ext.NewBidderParamsValidator("../../static/bidder-params")
+	if err != nil {
+		t.Fatalf("Failed to fetch the json-schemas. %v", err)
+	}
+
+	for _, invalidParam := range invalidParams {
+		if err := validator.Validate(openrtb_ext.BidderMocktioneer, json.RawMessage(invalidParam)); err == nil {
+			t.Errorf("Schema allowed unexpected params: %s", invalidParam)
+		}
+	}
+}
+
+var validParams = []string{
+	`{}`,
+	`{"bid": 1.5}`,
+	`{"bid": 0}`,
+	`{"bidFloorMultiplier": 1.5}`,
+	`{"bid": 2, "bidFloorMultiplier": 1.5}`,
+	`{"mediaType": "video"}`,
+	`{"mediaType": "audio"}`,
+	`{"expectBidCount": 0}`,
+	`{"expectBidCount": 2}`,
+	`{"delayResponseMs": 0}`,
+	`{"delayResponseMs": 5000}`,
+	`{"scenario": "no-bid"}`,
+	`{"forceError": true}`,
+	`{"ortbVersion": "2.5"}`,
+	`{"adm": "<div>ad</div>"}`,
+	`{"cur": "EUR"}`,
+	`{"connGroup": "group-1"}`,
+	`{"winNotice": "https://win.example.com/notice?price=${AUCTION_PRICE}"}`,
+	`{"priceFloor": 0}`,
+	`{"priceFloor": 1.5, "priceFloorCur": "EUR"}`,
+	`{"seed": 42}`,
+	`{"seed": -1}`,
+	`{"expectEndpoint": "https://mocktioneer.test/openrtb2/auction"}`,
+	`{"statusCode": 204}`,
+	`{"statusCode": 503}`,
+}
+
+var invalidParams = []string{
+	``,
+	`null`,
+	`true`,
+	`5`,
+	`[]`,
+	`{"bid": "1.5"}`,
+	`{"bid": -1}`,
+	`{"bidFloorMultiplier": 0}`,
+	`{"bidFloorMultiplier": -2}`,
+	`{"bidFloorMultiplier": "2"}`,
+	`{"mediaType": "ctv"}`,
+	`{"mediaType": 1}`,
+	`{"expectBidCount": -1}`,
+	`{"expectBidCount": 1.5}`,
+	`{"delayResponseMs": -1}`,
+	`{"delayResponseMs": 5001}`,
+	`{"scenario": ""}`,
+	`{"scenario": "no bid"}`,
+	`{"scenario": 1}`,
+	`{"forceError": "true"}`,
+	`{"ortbVersion": "3.0"}`,
+	`{"ortbVersion": 2.5}`,
+	`{"adm": 1}`,
+	`{"adm": "` + strings.Repeat("a", 65537) + `"}`,
+	`{"cur": "eur"}`,
+	`{"cur": "EURO"}`,
+	`{"connGroup": ""}`,
+	`{"connGroup": 1}`,
+	`{"winNotice": "http://win.example.com/notice"}`,
+	`{"priceFloor": -1}`,
+	`{"priceFloor": "1.5"}`,
+	`{"priceFloorCur": "eur"}`,
+	`{"seed": 1.5}`,
+	`{"seed": "42"}`,
+	`{"expectEndpoint": 1}`,
+	`{"expectEndpoint": "not a url"}`,
+	`{"statusCode": 99}`,
+	`{"statusCode": 600}`,
+	`{"statusCode": "204"}`,
+}
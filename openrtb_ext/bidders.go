@@ -181,6 +181,7 @@ var coreBidderNames []BidderName = []BidderName{
 	BidderMobfoxpb,
 	BidderMobileFuse,
 	BidderMobkoi,
+	BidderMocktioneer,
 	BidderMotorik,
 	BidderNativery,
 	BidderNativo,
@@ -553,6 +554,7 @@ const (
 	BidderMobfoxpb          BidderName = "mobfoxpb"
 	BidderMobileFuse        BidderName = "mobilefuse"
 	BidderMobkoi            BidderName = "mobkoi"
+	BidderMocktioneer       BidderName = "mocktioneer"
 	BidderMotorik           BidderName = "motorik"
 	BidderNativery          BidderName = "nativery"
 	BidderNativo            BidderName = "nativo"
@@ -0,0 +1,22 @@
+package openrtb_ext
+
+// ExtMocktioneer defines the contract for bidrequest.imp[i].ext.prebid.bidder.mocktioneer
+type ExtMocktioneer struct {
+	Bid                *float64 `json:"bid,omitempty"`
+	BidFloorMultiplier float64  `json:"bidFloorMultiplier,omitempty"`
+	MediaType          string   `json:"mediaType,omitempty"`
+	ExpectBidCount     *int     `json:"expectBidCount,omitempty"`
+	DelayResponseMs    int      `json:"delayResponseMs,omitempty"`
+	Scenario           string   `json:"scenario,omitempty"`
+	ForceError         bool     `json:"forceError,omitempty"`
+	OrtbVersion        string   `json:"ortbVersion,omitempty"`
+	AdM                string   `json:"adm,omitempty"`
+	Cur                string   `json:"cur,omitempty"`
+	ConnGroup          string   `json:"connGroup,omitempty"`
+	WinNotice          string   `json:"winNotice,omitempty"`
+	PriceFloor         *float64 `json:"priceFloor,omitempty"`
+	PriceFloorCur      string   `json:"priceFloorCur,omitempty"`
+	Seed               *int64   `json:"seed,omitempty"`
+	ExpectEndpoint     string   `json:"expectEndpoint,omitempty"`
+	StatusCode         int      `json:"statusCode,omitempty"`
+}